@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// Batchable reports whether an rpc can be dispatched from a batch, async
+// and idempotent commands having their own response contracts
+func (a APIPath) Batchable() bool {
+	return a.Kind == KindCommand && !a.Async && !a.Idempotent
+}
+
+// generateBatchHandler generates the handler dispatching the commands of a
+// batch to the application
+func generateBatchHandler(
+	g *protogen.GeneratedFile,
+	srv Server,
+	ctrlName string,
+) {
+	contextPackage := protogen.GoImportPath("context")
+	ginPackage := protogen.GoImportPath("github.com/gin-gonic/gin")
+	protoPackage := protogen.GoImportPath("google.golang.org/protobuf/proto")
+	runtimePackage := protogen.GoImportPath(RuntimePackage)
+
+	g.P("// commandBatch dispatches the commands of a batch to the application")
+	g.P("func (p *", ctrlName, ") commandBatch(ctx *", ginPackage.Ident("Context"), ") {")
+	g.P("defer ", runtimePackage.Ident("Observe"), "(ctx, p.observers, ", batchInfoVar(srv), ")()")
+	g.P("c, err := ", runtimePackage.Ident("Context"), "(ctx, p.contextFactory)")
+	g.P("if err != nil {")
+	g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
+	g.P("	return")
+	g.P("}")
+	g.P(runtimePackage.Ident("ServeBatch"), "(")
+	g.P("ctx,")
+	g.P("c,")
+	g.P("p.batch,")
+	g.P(runtimePackage.Ident("DecodeOptions"), "{MaxBodyBytes: ", srv.Batch.MaxBodyBytes, "},")
+	g.P("map[string]", runtimePackage.Ident("BatchHandler"), "{")
+	for _, rpc := range srv.Paths {
+		if !rpc.Batchable() {
+			continue
+		}
+		g.P("\"", rpc.Method.Desc.Name(), "\": func(")
+		g.P("c ", contextPackage.Ident("Context"), ",")
+		g.P("decode func(", protoPackage.Ident("Message"), ") error,")
+		g.P(") (", protoPackage.Ident("Message"), ", error) {")
+		g.P("if p.guarded[", fullNameConst(srv, rpc), "] {")
+		g.P("	return nil, ", runtimePackage.Ident("ErrBatchMiddleware"))
+		g.P("}")
+		if auth := rpc.Authorization; auth != nil && !auth.AllowAnonymous {
+			g.P("if err := ", runtimePackage.Ident("Authorize"), "(")
+			g.P("c,")
+			g.P("p.authorizer,")
+			g.P(fullNameConst(srv, rpc), ",")
+			generateRequirements(g, auth)
+			g.P("); err != nil {")
+			g.P("	return nil, err")
+			g.P("}")
+		}
+		g.P("body := ", rpc.Method.Input.GoIdent, "{}")
+		g.P("if err := decode(&body); err != nil {")
+		g.P("	return nil, err")
+		g.P("}")
+		if len(rpc.Bindings) != 0 {
+			generateBind(g, rpc)
+			g.P("	return nil, err")
+			g.P("}")
+		}
+		g.P("return ", dispatcher(rpc), ".", rpc.Method.GoName, "(c, &body)")
+		g.P("},")
+	}
+	g.P("},")
+	g.P(")")
+	g.P("}")
+	g.P()
+}
+
+// batchSchema is the name of the schema of the commands of a batch
+func batchSchema(srv Server) string {
+	return srv.Service.GoName + "BatchCommand"
+}
+
+// batchResultSchema is the name of the schema of the results of a batch
+func batchResultSchema(srv Server) string {
+	return srv.Service.GoName + "BatchResult"
+}
+
+// generateOpenAPIBatchPath generates the batch route of a service
+func generateOpenAPIBatchPath(g *protogen.GeneratedFile, srv Server) {
+	if srv.Batch == nil {
+		return
+	}
+	g.P("  ", srv.Batch.Path, ":")
+	g.P("    post:")
+	g.P("      summary: ", yamlString("Run a batch of "+srv.Service.GoName+" commands"))
+	g.P("      description: ", yamlString(
+		"Dispatches each command of the batch, answering with the result or "+
+			"error of each in order",
+	))
+	g.P("      requestBody:")
+	g.P("        content:")
+	g.P("          application/json:")
+	g.P("            schema:")
+	g.P("              type: array")
+	g.P("              items:")
+	g.P("                $ref: '#/components/schemas/", batchSchema(srv), "'")
+	g.P("        required: true")
+	if srv.Batch.MaxBodyBytes > 0 {
+		g.P("        x-max-body-size: ", srv.Batch.MaxBodyBytes)
+	}
+	g.P("      responses:")
+	g.P("        '200':")
+	g.P("          description: The results of the commands, in order")
+	g.P("          content:")
+	g.P("            application/json:")
+	g.P("              schema:")
+	g.P("                type: array")
+	g.P("                items:")
+	g.P("                  $ref: '#/components/schemas/", batchResultSchema(srv), "'")
+	g.P("        '400':")
+	g.P("          description: The batch is empty, too large or malformed")
+	if srv.Batch.MaxBodyBytes > 0 {
+		g.P("        '413':")
+		g.P("          description: The request body is too large")
+	}
+}
+
+// generateOpenAPIBatchSchemas generates the schemas of the commands and
+// results of the batches of a service
+func generateOpenAPIBatchSchemas(g *protogen.GeneratedFile, srv Server) {
+	if srv.Batch == nil {
+		return
+	}
+	g.P("    ", batchSchema(srv), ":")
+	g.P("      oneOf:")
+	outputs := []string{}
+	seen := map[string]struct{}{}
+	for _, api := range srv.Paths {
+		if !api.Batchable() {
+			continue
+		}
+		g.P("        - type: object")
+		g.P("          properties:")
+		g.P("            command:")
+		g.P("              type: string")
+		g.P("              enum: [", api.Method.Desc.Name(), "]")
+		g.P("            payload:")
+		g.P("              $ref: '#/components/schemas/", api.Method.Input.GoIdent.GoName, "'")
+		g.P("          required: [command]")
+
+		output := api.Method.Output.GoIdent.GoName
+		if _, ok := seen[output]; !ok {
+			seen[output] = struct{}{}
+			outputs = append(outputs, output)
+		}
+	}
+	g.P("    ", batchResultSchema(srv), ":")
+	g.P("      type: object")
+	g.P("      properties:")
+	g.P("        status:")
+	g.P("          type: integer")
+	g.P("          description: Http status of the command, 424 when skipped after a failure")
+	g.P("        result:")
+	g.P("          oneOf:")
+	for _, output := range outputs {
+		g.P("            - $ref: '#/components/schemas/", output, "'")
+	}
+	g.P("        error:")
+	g.P("          type: string")
+	g.P("      required: [status]")
+}
@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"techunicorn.com/protoc-gen-gocqrshttp/custom/annotations"
+)
+
+// fieldOption reads the custom.field option of a field, returning nil when
+// it is not set
+func fieldOption(field *protogen.Field) *annotations.Field {
+	options, _ := field.Desc.Options().(*descriptorpb.FieldOptions)
+	if options == nil || !proto.HasExtension(options, annotations.E_Field) {
+		return nil
+	}
+	opt, _ := proto.GetExtension(options, annotations.E_Field).(*annotations.Field)
+	return opt
+}
+
+// isBound reports whether a field is bound to a request header or cookie,
+// and thus left out of request bodies
+func isBound(field *protogen.Field) bool {
+	opt := fieldOption(field)
+	return opt.GetHeader() != "" || opt.GetCookie() != ""
+}
+
+// isResponseHeader reports whether a field is mapped to a response header,
+// and thus left out of the response bodies of the rpcs it is the output of
+func isResponseHeader(field *protogen.Field) bool {
+	return fieldOption(field).GetResponseHeader() != ""
+}
+
+// fieldDescription describes the header or cookie a field is bound or mapped
+// to with the leading comment of the field
+func fieldDescription(field *protogen.Field) string {
+	description := strings.TrimSpace(string(field.Comments.Leading))
+	if description == "" {
+		description = "Value of the " + field.Desc.JSONName() + " field"
+	}
+	return description
+}
+
+// scalarSchema is the OpenAPI type and format of the values of a scalar kind
+func scalarSchema(kind protoreflect.Kind) (string, string) {
+	switch kind {
+	case protoreflect.BoolKind:
+		return "boolean", ""
+	case protoreflect.Int32Kind,
+		protoreflect.Sint32Kind,
+		protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind,
+		protoreflect.Fixed32Kind:
+		return "integer", "int32"
+	case protoreflect.Int64Kind,
+		protoreflect.Sint64Kind,
+		protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind,
+		protoreflect.Fixed64Kind:
+		return "integer", "int64"
+	case protoreflect.FloatKind:
+		return "number", "float"
+	case protoreflect.DoubleKind:
+		return "number", "double"
+	}
+	return "string", ""
+}
+
+// generateBind generates the condition binding the fields of the input of an
+// rpc to the request headers and cookies, the caller handling its error
+func generateBind(g *protogen.GeneratedFile, rpc APIPath) {
+	runtimePackage := protogen.GoImportPath(RuntimePackage)
+
+	g.P("if err := ", runtimePackage.Ident("Bind"), "(ctx, &body,")
+	for _, binding := range rpc.Bindings {
+		in := runtimePackage.Ident("InHeader")
+		switch binding.In {
+		case InCookie:
+			in = runtimePackage.Ident("InCookie")
+		case InQuery:
+			in = runtimePackage.Ident("InQuery")
+		}
+		g.P(runtimePackage.Ident("Binding"), "{")
+		g.P("Field: ", strconv.Quote(string(binding.Field.Desc.Name())), ",")
+		g.P("In: ", in, ",")
+		g.P("Name: ", strconv.Quote(binding.Name), ",")
+		if binding.Required {
+			g.P("Required: true,")
+		}
+		g.P("},")
+	}
+	g.P("); err != nil {")
+}
@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// busName is the name of the bus of the rpcs of a kind, empty for the kinds
+// without one
+func busName(srv Server, kind Kind) string {
+	switch kind {
+	case KindCommand:
+		return srv.Service.GoName + "CommandBus"
+	case KindQuery:
+		return srv.Service.GoName + "QueryBus"
+	}
+	return ""
+}
+
+// busPaths lists the rpcs of a service dispatched by the bus of a kind
+func busPaths(srv Server, kind Kind) []APIPath {
+	pths := []APIPath{}
+	for _, api := range srv.Paths {
+		if api.Kind == kind {
+			pths = append(pths, api)
+		}
+	}
+	return pths
+}
+
+// dispatcher is the expression a controller dispatches an rpc with, the
+// bus of its kind or the application itself
+func dispatcher(api APIPath) string {
+	switch api.Kind {
+	case KindCommand:
+		return "p.commands"
+	case KindQuery:
+		return "p.queries"
+	}
+	return "p.app"
+}
+
+// GenerateBuses generates the command and query buses of the services,
+// dispatching their inputs to the application independently of http
+func GenerateBuses(srvs []Server, g *protogen.GeneratedFile) {
+	for _, srv := range srvs {
+		generateBus(g, srv, KindCommand, "ErrUnknownCommand")
+		generateBus(g, srv, KindQuery, "ErrUnknownQuery")
+	}
+}
+
+func generateBus(
+	g *protogen.GeneratedFile,
+	srv Server,
+	kind Kind,
+	unknown string,
+) {
+	contextPackage := protogen.GoImportPath("context")
+	fmtPackage := protogen.GoImportPath("fmt")
+	protoPackage := protogen.GoImportPath("google.golang.org/protobuf/proto")
+	runtimePackage := protogen.GoImportPath(RuntimePackage)
+
+	pths := busPaths(srv, kind)
+	if len(pths) == 0 {
+		return
+	}
+	name := busName(srv, kind)
+	app := srv.Service.GoName + "HTTPServer"
+
+	g.P("// ", name, " dispatches the ", kind.Plural(), " of ", srv.Service.GoName, " to the application")
+	g.P("// through a pipeline of behaviors")
+	g.P("type ", name, " struct {")
+	g.P("app ", app)
+	g.P("behaviors []", runtimePackage.Ident("Behavior"))
+	g.P("}")
+	g.P()
+	g.P("// New", name, " creates a bus dispatching to app through behaviors, the")
+	g.P("// first being the outermost")
+	g.P("func New", name, "(")
+	g.P("app ", app, ",")
+	g.P("behaviors ...", runtimePackage.Ident("Behavior"), ",")
+	g.P(") *", name, " {")
+	g.P("return &", name, "{app: app, behaviors: behaviors}")
+	g.P("}")
+	g.P()
+
+	for _, api := range pths {
+		input, output := api.Method.Input.GoIdent, api.Method.Output.GoIdent
+		g.P("// ", api.Method.GoName, " dispatches a ", input.GoName)
+		g.P("func (b *", name, ") ", api.Method.GoName, "(")
+		g.P("c ", contextPackage.Ident("Context"), ",")
+		g.P("req *", input, ",")
+		g.P(") (*", output, ", error) {")
+		g.P("res, err := ", runtimePackage.Ident("Dispatch"), "(")
+		g.P("c,")
+		g.P("b.behaviors,")
+		g.P(fullNameConst(srv, api), ",")
+		g.P("req,")
+		g.P(
+			"func(c ",
+			contextPackage.Ident("Context"),
+			", req ",
+			protoPackage.Ident("Message"),
+			") (",
+			protoPackage.Ident("Message"),
+			", error) {",
+		)
+		g.P("res, err := b.app.", api.Method.GoName, "(c, req.(*", input, "))")
+		g.P("if err != nil {")
+		g.P("	return nil, err")
+		g.P("}")
+		g.P("return res, nil")
+		g.P("},")
+		g.P(")")
+		g.P("if err != nil {")
+		g.P("	return nil, err")
+		g.P("}")
+		g.P("out, ok := res.(*", output, ")")
+		g.P("if !ok {")
+		g.P("	return nil, ", fmtPackage.Ident("Errorf"), "(\"%s: unexpected result %T\", ", fullNameConst(srv, api), ", res)")
+		g.P("}")
+		g.P("return out, nil")
+		g.P("}")
+		g.P()
+	}
+
+	// rpcs sharing an input are dispatched to the first of them
+	seen := map[string]struct{}{}
+	unique := []APIPath{}
+	for _, api := range pths {
+		full := string(api.Method.Input.Desc.FullName())
+		if _, ok := seen[full]; !ok {
+			seen[full] = struct{}{}
+			unique = append(unique, api)
+		}
+	}
+
+	g.P("// Dispatch dispatches a ", kind, " by its type, failing with")
+	g.P("// runtime.", unknown, " for any other message")
+	g.P("func (b *", name, ") Dispatch(")
+	g.P("c ", contextPackage.Ident("Context"), ",")
+	g.P("req ", protoPackage.Ident("Message"), ",")
+	g.P(") (", protoPackage.Ident("Message"), ", error) {")
+	g.P("switch req := req.(type) {")
+	for _, api := range unique {
+		g.P("case *", api.Method.Input.GoIdent, ":")
+		g.P("res, err := b.", api.Method.GoName, "(c, req)")
+		g.P("if err != nil {")
+		g.P("	return nil, err")
+		g.P("}")
+		g.P("return res, nil")
+	}
+	g.P("}")
+	g.P("return nil, ", runtimePackage.Ident(unknown))
+	g.P("}")
+	g.P()
+	g.P("// NewInput creates an empty ", kind, " from the full name of its message,")
+	g.P("// reporting whether the bus dispatches it")
+	g.P("func (b *", name, ") NewInput(name string) (", protoPackage.Ident("Message"), ", bool) {")
+	g.P("switch name {")
+	for _, api := range unique {
+		g.P("case \"", api.Method.Input.Desc.FullName(), "\":")
+		g.P("return &", api.Method.Input.GoIdent, "{}, true")
+	}
+	g.P("}")
+	g.P("return nil, false")
+	g.P("}")
+	g.P()
+}
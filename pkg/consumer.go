@@ -0,0 +1,48 @@
+package pkg
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// GenerateConsumers generates the queue consumers of the commands of the
+// services, dispatching them through their command bus
+func GenerateConsumers(srvs []Server, g *protogen.GeneratedFile) {
+	contextPackage := protogen.GoImportPath("context")
+	runtimePackage := protogen.GoImportPath(RuntimePackage)
+
+	for _, srv := range srvs {
+		if len(busPaths(srv, KindCommand)) == 0 {
+			continue
+		}
+		name := srv.Service.GoName + "Consumer"
+		bus := busName(srv, KindCommand)
+
+		g.P("// ", name, " dispatches the commands of ", srv.Service.GoName, " received")
+		g.P("// from a queue")
+		g.P("type ", name, " struct {")
+		g.P("bus *", bus)
+		g.P("}")
+		g.P()
+		g.P("// New", name, " creates a consumer dispatching through bus")
+		g.P("func New", name, "(bus *", bus, ") *", name, " {")
+		g.P("return &", name, "{bus: bus}")
+		g.P("}")
+		g.P()
+		g.P("// Consume is a runtime.ConsumeFunc decoding a message into the command")
+		g.P("// named by its type header and dispatching it")
+		g.P("func (c *", name, ") Consume(")
+		g.P("ctx ", contextPackage.Ident("Context"), ",")
+		g.P("msg ", runtimePackage.Ident("Message"), ",")
+		g.P("acker ", runtimePackage.Ident("Acker"), ",")
+		g.P(") error {")
+		g.P("return ", runtimePackage.Ident("Consume"), "(")
+		g.P("ctx,")
+		g.P("msg,")
+		g.P("acker,")
+		g.P("c.bus.NewInput,")
+		g.P("c.bus.Dispatch,")
+		g.P(")")
+		g.P("}")
+		g.P()
+	}
+}
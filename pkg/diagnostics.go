@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Rule IDs of the conventions enforced by the generator
+const (
+	RuleInputKind      = "CQRS001"
+	RuleInputReuse     = "CQRS002"
+	RuleOperation      = "CQRS003"
+	RuleRouteConflict  = "CQRS004"
+	RuleCommandOption  = "CQRS005"
+	RuleQueryOption    = "CQRS006"
+	RuleEvent          = "CQRS007"
+	RuleBinding        = "CQRS008"
+	RuleResponse       = "CQRS009"
+	RuleDocumentation  = "DOC001"
+	RuleSecurityScheme = "SEC001"
+)
+
+// Position is a location within a proto file, lines and columns starting at 1
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// SourcePosition resolves the position of a descriptor from the
+// SourceCodeInfo of its file
+func SourcePosition(
+	file *protogen.File,
+	desc protoreflect.Descriptor,
+) Position {
+	loc := file.Desc.SourceLocations().ByDescriptor(desc)
+	return Position{
+		File:   file.Desc.Path(),
+		Line:   loc.StartLine + 1,
+		Column: loc.StartColumn + 1,
+	}
+}
+
+// Diagnostic is a single convention violation
+type Diagnostic struct {
+	Position Position
+	Rule     string
+	Message  string
+	Fix      string
+}
+
+func (d Diagnostic) String() string {
+	out := fmt.Sprintf("%s: %s [%s]", d.Position, d.Message, d.Rule)
+	if d.Fix != "" {
+		out += "\n\tfix: " + d.Fix
+	}
+	return out
+}
+
+// Diagnostics collects every violation found during a run so that they can
+// be reported together
+type Diagnostics []Diagnostic
+
+// Report records a violation found at the given descriptor, once even when
+// found again through another rpc using the same message
+func (d *Diagnostics) Report(
+	file *protogen.File,
+	desc protoreflect.Descriptor,
+	rule string,
+	fix string,
+	format string,
+	args ...interface{},
+) {
+	diag := Diagnostic{
+		Position: SourcePosition(file, desc),
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+		Fix:      fix,
+	}
+	for _, reported := range *d {
+		if reported == diag {
+			return
+		}
+	}
+	*d = append(*d, diag)
+}
+
+// Err returns the diagnostics as an error, or nil if there are none
+func (d Diagnostics) Err() error {
+	if len(d) == 0 {
+		return nil
+	}
+	return d
+}
+
+func (d Diagnostics) Error() string {
+	sorted := make(Diagnostics, len(d))
+	copy(sorted, d)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Position, sorted[j].Position
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	lines := make([]string, len(sorted))
+	for i, diag := range sorted {
+		lines[i] = diag.String()
+	}
+	return fmt.Sprintf(
+		"%d convention violation(s)\n%s",
+		len(sorted),
+		strings.Join(lines, "\n"),
+	)
+}
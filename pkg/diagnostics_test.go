@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// diagnosticsFile builds a file declaring the Order message on line 3 and its
+// id field on line 4
+func diagnosticsFile(t *testing.T) *protogen.File {
+	t.Helper()
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("orders.proto"),
+		Package: proto.String("orders"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/orders")},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Order"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("id"),
+				Number:   proto.Int32(1),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				JsonName: proto.String("id"),
+			}},
+		}},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{Path: []int32{4, 0}, Span: []int32{2, 0, 4, 1}},
+				{Path: []int32{4, 0, 2, 0}, Span: []int32{3, 2, 17}},
+			},
+		},
+	}
+	plugin, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{fd.GetName()},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{fd},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plugin.Files[0]
+}
+
+func TestDiagnostics(t *testing.T) {
+	file := diagnosticsFile(t)
+	msg := file.Messages[0]
+	field := msg.Fields[0]
+
+	diags := Diagnostics{}
+	if diags.Err() != nil {
+		t.Error("no diagnostics reported as an error")
+	}
+	diags.Report(file, field.Desc, RuleBinding, "bind it", "field %s is bound", field.Desc.FullName())
+	diags.Report(file, msg.Desc, RuleInputKind, "", "message %s", msg.Desc.FullName())
+	// found again through another rpc
+	diags.Report(file, field.Desc, RuleBinding, "bind it", "field %s is bound", field.Desc.FullName())
+	diags.Report(file, field.Desc, RuleBinding, "bind it", "field %s is required", field.Desc.FullName())
+
+	if len(diags) != 3 {
+		t.Fatalf("%d diagnostics, want 3: %v", len(diags), diags)
+	}
+	if want := (Position{File: "orders.proto", Line: 4, Column: 3}); diags[0].Position != want {
+		t.Errorf("position = %v, want %v", diags[0].Position, want)
+	}
+	if want := (Position{File: "orders.proto", Line: 3, Column: 1}); diags[1].Position != want {
+		t.Errorf("position = %v, want %v", diags[1].Position, want)
+	}
+
+	err := diags.Err()
+	if err == nil {
+		t.Fatal("diagnostics not reported as an error")
+	}
+	want := strings.Join([]string{
+		"3 convention violation(s)",
+		"orders.proto:3:1: message orders.Order [CQRS001]",
+		"orders.proto:4:3: field orders.Order.id is bound [CQRS008]",
+		"\tfix: bind it",
+		"orders.proto:4:3: field orders.Order.id is required [CQRS008]",
+		"\tfix: bind it",
+	}, "\n")
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+}
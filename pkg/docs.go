@@ -0,0 +1,21 @@
+package pkg
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// CommentDocumentation derives the summary and description of an rpc from
+// its leading comment, the summary being the first line of the comment
+func CommentDocumentation(
+	comments protogen.Comments,
+) (summary string, description string) {
+	lines := []string{}
+	for _, line := range strings.Split(string(comments), "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	description = strings.TrimSpace(strings.Join(lines, "\n"))
+	summary, _, _ = strings.Cut(description, "\n")
+	return
+}
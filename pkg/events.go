@@ -0,0 +1,190 @@
+package pkg
+
+import (
+	"encoding/json"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// MessageIndex finds the messages of every file of a request by full name
+type MessageIndex map[protoreflect.FullName]*protogen.Message
+
+// IndexMessages indexes the messages of files, nested ones included
+func IndexMessages(files []*protogen.File) MessageIndex {
+	index := MessageIndex{}
+	var add func(msgs []*protogen.Message)
+	add = func(msgs []*protogen.Message) {
+		for _, msg := range msgs {
+			index[msg.Desc.FullName()] = msg
+			add(msg.Messages)
+		}
+	}
+	for _, file := range files {
+		add(file.Messages)
+	}
+	return index
+}
+
+// Resolve finds a message by full name, or by name relative to pkg
+func (i MessageIndex) Resolve(
+	pkg protoreflect.FullName,
+	name string,
+) *protogen.Message {
+	if msg, ok := i[protoreflect.FullName(name)]; ok {
+		return msg
+	}
+	return i[protoreflect.FullName(string(pkg)+"."+name)]
+}
+
+// serviceEvents lists the events emitted by the commands of a service, each
+// with the full names of the rpcs emitting it
+func serviceEvents(srv Server) ([]*protogen.Message, map[*protogen.Message][]string) {
+	events := []*protogen.Message{}
+	emitters := map[*protogen.Message][]string{}
+	for _, api := range srv.Paths {
+		for _, event := range api.Emits {
+			if _, ok := emitters[event]; !ok {
+				events = append(events, event)
+			}
+			emitters[event] = append(
+				emitters[event],
+				string(api.Method.Desc.FullName()),
+			)
+		}
+	}
+	return events, emitters
+}
+
+// EmitsEvents reports whether a command of the services emits events
+func EmitsEvents(srvs []Server) bool {
+	for _, srv := range srvs {
+		if events, _ := serviceEvents(srv); len(events) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateEventPublishers generates the typed publishers of the events
+// emitted by the commands of the services
+func GenerateEventPublishers(srvs []Server, g *protogen.GeneratedFile) {
+	contextPackage := protogen.GoImportPath("context")
+	runtimePackage := protogen.GoImportPath(RuntimePackage)
+
+	for _, srv := range srvs {
+		events, _ := serviceEvents(srv)
+		if len(events) == 0 {
+			continue
+		}
+		name := srv.Service.GoName + "EventPublisher"
+		impl := ToPrivateName(name)
+
+		g.P("// ", name, " publishes the events emitted by the commands of")
+		g.P("// ", srv.Service.GoName)
+		g.P("type ", name, " interface {")
+		for _, event := range events {
+			g.P("// Publish", event.GoIdent.GoName, " publishes a ", event.Desc.FullName())
+			g.P(
+				"Publish", event.GoIdent.GoName, "(ctx ",
+				contextPackage.Ident("Context"),
+				", event *", event.GoIdent, ") error",
+			)
+		}
+		g.P("}")
+		g.P()
+		g.P("// New", name, " adapts publish, called with the full name of each")
+		g.P("// event, to a ", name)
+		g.P("func New", name, "(publish ", runtimePackage.Ident("PublishFunc"), ") ", name, " {")
+		g.P("return ", impl, "{publish: publish}")
+		g.P("}")
+		g.P()
+		g.P("type ", impl, " struct {")
+		g.P("publish ", runtimePackage.Ident("PublishFunc"))
+		g.P("}")
+		g.P()
+		for _, event := range events {
+			g.P("func (p ", impl, ") Publish", event.GoIdent.GoName, "(")
+			g.P("ctx ", contextPackage.Ident("Context"), ",")
+			g.P("event *", event.GoIdent, ",")
+			g.P(") error {")
+			g.P("return p.publish(ctx, \"", event.Desc.FullName(), "\", event)")
+			g.P("}")
+			g.P()
+		}
+	}
+}
+
+// GenerateAsyncAPI generates the AsyncAPI document of the events emitted by
+// the commands of the services, one channel per event
+func GenerateAsyncAPI(
+	srvs []Server,
+	g *protogen.GeneratedFile,
+	gjson *protogen.GeneratedFile,
+	file *protogen.File,
+) error {
+	g.P("asyncapi: 2.6.0")
+	g.P("info:")
+	g.P("  title: ", file.Desc.Package())
+	g.P("  version: ", "'1.0'")
+	g.P("defaultContentType: ", MediaTypes[0])
+	g.P("channels:")
+
+	all := []*protogen.Message{}
+	seen := map[*protogen.Message]struct{}{}
+	for _, srv := range srvs {
+		events, emitters := serviceEvents(srv)
+		for _, event := range events {
+			if _, ok := seen[event]; ok {
+				continue
+			}
+			seen[event] = struct{}{}
+			all = append(all, event)
+
+			g.P("  ", event.Desc.FullName(), ":")
+			g.P("    description: ", yamlString(
+				"Emitted by "+strings.Join(emitters[event], ", "),
+			))
+			g.P("    subscribe:")
+			g.P("      operationId: on", event.GoIdent.GoName)
+			g.P("      message:")
+			g.P("        $ref: '#/components/messages/", event.GoIdent.GoName, "'")
+		}
+	}
+
+	g.P("components:")
+	g.P("  messages:")
+	for _, event := range all {
+		summary, _ := CommentDocumentation(event.Comments.Leading)
+		g.P("    ", event.GoIdent.GoName, ":")
+		g.P("      name: ", event.Desc.FullName())
+		g.P("      summary: ", yamlString(summary))
+		g.P("      contentType: ", MediaTypes[0])
+		g.P("      payload:")
+		g.P("        $ref: '#/components/schemas/", event.GoIdent.GoName, "'")
+	}
+	g.P("  schemas:")
+	schemas := map[string]struct{}{}
+	for _, event := range all {
+		if err := generateOpenAPIComponentSchema(g, schemas, event); err != nil {
+			return err
+		}
+	}
+
+	raw, err := g.Content()
+	if err != nil {
+		return err
+	}
+	doc := map[string]interface{}{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	jsonraw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	gjson.P(string(jsonraw))
+	return nil
+}
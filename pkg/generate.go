@@ -4,12 +4,14 @@ package pkg
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
 
 	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"gopkg.in/yaml.v3"
+	"techunicorn.com/protoc-gen-gocqrshttp/custom/annotations"
 )
 
 // GenerateHTTPServers generates http servers
@@ -20,11 +22,71 @@ func GenerateHTTPServers(
 ) error {
 	contextPackage := protogen.GoImportPath("context")
 	ginPackage := protogen.GoImportPath("github.com/gin-gonic/gin")
-	protojsonPackage := protogen.GoImportPath("google.golang.org/protobuf/encoding/protojson")
-	ioutilPackage := protogen.GoImportPath("io/ioutil")
+	httpPackage := protogen.GoImportPath("net/http")
+	pathPackage := protogen.GoImportPath("path")
+	protoPackage := protogen.GoImportPath("google.golang.org/protobuf/proto")
+	runtimePackage := protogen.GoImportPath(RuntimePackage)
+
+	g.P("// This is a compile-time assertion to ensure that this generated file")
+	g.P("// is compatible with the runtime package it is being compiled against.")
+	g.P("const _ = ", runtimePackage.Ident(fmt.Sprintf(
+		"SupportPackageIsVersion%d",
+		RuntimeVersion,
+	)))
+	g.P()
 
 	for _, srv := range srvs {
 		intname := srv.Service.GoName + "HTTPServer"
+		g.P("const (")
+		for _, rpc := range srv.Paths {
+			g.P(fullNameConst(srv, rpc), " = \"", rpc.Method.Desc.FullName(), "\"")
+		}
+		g.P(")")
+		g.P()
+		g.P("var (")
+		for _, rpc := range srv.Paths {
+			g.P(rpcInfoVar(srv, rpc), " = ", runtimePackage.Ident("RPCInfo"), "{")
+			g.P("Service: \"", srv.Service.Desc.FullName(), "\",")
+			g.P("Method: ", fullNameConst(srv, rpc), ",")
+			g.P("Kind: \"", rpc.Kind, "\",")
+			g.P("Route: \"", rpc.Path, "\",")
+			g.P("}")
+		}
+		if srv.OperationsPath != "" {
+			g.P("// ", operationsInfoVar(srv), " describes the route polling the operations")
+			g.P("// of the asynchronous commands")
+			g.P(operationsInfoVar(srv), " = ", runtimePackage.Ident("RPCInfo"), "{")
+			g.P("Service: \"", srv.Service.Desc.FullName(), "\",")
+			g.P("Method: \"", srv.Service.Desc.FullName(), " ", OperationsRoute, "\",")
+			g.P("Kind: \"", KindQuery, "\",")
+			g.P("Route: \"", srv.OperationsPath, "/:id\",")
+			g.P("}")
+		}
+		if srv.Batch != nil {
+			g.P("// ", batchInfoVar(srv), " describes the batch route")
+			g.P(batchInfoVar(srv), " = ", runtimePackage.Ident("RPCInfo"), "{")
+			g.P("Service: \"", srv.Service.Desc.FullName(), "\",")
+			g.P("Method: \"", srv.Service.Desc.FullName(), " ", BatchRoute, "\",")
+			g.P("Kind: \"", KindCommand, "\",")
+			g.P("Route: \"", srv.Batch.Path, "\",")
+			g.P("}")
+		}
+		g.P(")")
+		g.P()
+		g.P("// ", rpcsVar(srv), " describes every rpc of ", srv.Service.GoName, ", and its batch and")
+		g.P("// operations routes")
+		g.P("var ", rpcsVar(srv), " = []", runtimePackage.Ident("RPCInfo"), "{")
+		for _, rpc := range srv.Paths {
+			g.P(rpcInfoVar(srv, rpc), ",")
+		}
+		if srv.OperationsPath != "" {
+			g.P(operationsInfoVar(srv), ",")
+		}
+		if srv.Batch != nil {
+			g.P(batchInfoVar(srv), ",")
+		}
+		g.P("}")
+		g.P()
 		g.P(fmt.Sprintf("// %s", srv.Service.GoName))
 		g.P("type ", intname, " interface {")
 		for _, rpc := range srv.Paths {
@@ -53,11 +115,34 @@ func GenerateHTTPServers(
 		ctrlName := ToPrivateName(srv.Service.GoName)
 		g.P("type ", ctrlName, " struct {")
 		g.P("app ", intname)
+		if len(busPaths(srv, KindCommand)) != 0 {
+			g.P("commands *", busName(srv, KindCommand))
+		}
+		if len(busPaths(srv, KindQuery)) != 0 {
+			g.P("queries *", busName(srv, KindQuery))
+		}
+		g.P("authorizer ", runtimePackage.Ident("Authorizer"))
+		g.P("contextFactory ", runtimePackage.Ident("ContextFactory"))
+		g.P("compression *", runtimePackage.Ident("Compression"))
+		g.P("idempotency ", runtimePackage.Ident("IdempotencyStore"))
+		g.P("observers []", runtimePackage.Ident("Observer"))
+		if srv.OperationsPath != "" {
+			g.P("operations ", runtimePackage.Ident("OperationStore"))
+			g.P("operationsPath string")
+		}
+		if srv.Batch != nil {
+			g.P("batch ", runtimePackage.Ident("BatchOptions"))
+			g.P("// guarded are the batchable rpcs whose route has method or tag")
+			g.P("// middlewares, which cannot be run for the commands of a batch")
+			g.P("guarded map[string]bool")
+		}
 		g.P("}")
 
 		for _, rpc := range srv.Paths {
 
-			g.P("// ", rpc.Description)
+			for _, line := range strings.Split(rpc.Description, "\n") {
+				g.P("// ", line)
+			}
 			g.P(
 				"func (p *",
 				ctrlName,
@@ -68,15 +153,46 @@ func GenerateHTTPServers(
 				") {",
 			)
 
-			g.P("body := ", rpc.Method.Input.GoIdent, "{}")
-			if rpc.HTTPMethod != "GET" {
-				// TODO if anything left in body
-				g.P("raw, err :=", ioutilPackage.Ident("ReadAll"), "(ctx.Request.Body)")
+			g.P(
+				"defer ",
+				runtimePackage.Ident("Observe"),
+				"(ctx, p.observers, ",
+				rpcInfoVar(srv, rpc),
+				")()",
+			)
+			// callers are authorized before the body of their request is read
+			g.P("c, err := ", runtimePackage.Ident("Context"), "(ctx, p.contextFactory)")
+			g.P("if err != nil {")
+			g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
+			g.P("	return")
+			g.P("}")
+			if auth := rpc.Authorization; auth != nil && !auth.AllowAnonymous {
+				g.P("if err := ", runtimePackage.Ident("Authorize"), "(")
+				g.P("c,")
+				g.P("p.authorizer,")
+				g.P(fullNameConst(srv, rpc), ",")
+				generateRequirements(g, auth)
+				g.P("); err != nil {")
+				g.P(runtimePackage.Ident("Error"), "(ctx, err)")
+				g.P("return")
+				g.P("}")
+			}
+			// operations are always json
+			if !rpc.Async {
+				g.P("codec, err := ", runtimePackage.Ident("Negotiate"), "(ctx)")
 				g.P("if err != nil {")
-				g.P("	ctx.Error(err)")
+				g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
+				g.P("	return")
+				g.P("}")
+			}
+			g.P("body := ", rpc.Method.Input.GoIdent, "{}")
+			if rpc.HasBody() {
+				g.P("if err := ", runtimePackage.Ident("Decode"), "(ctx, &body, ", runtimePackage.Ident("DecodeOptions"), "{")
+				g.P("MaxBodyBytes: ", rpc.MaxBodyBytes, ",")
+				g.P("}); err != nil {")
+				g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
 				g.P("	return")
 				g.P("}")
-				g.P(protojsonPackage.Ident("Unmarshal"), "(raw, &body)")
 			}
 			for _, qpm := range rpc.QueryParameters {
 				g.P("body.", qpm.ModelParameter, "= ctx.Query(\",", qpm.Key, "\")")
@@ -84,44 +200,269 @@ func GenerateHTTPServers(
 			for _, pth := range rpc.PathParameters {
 				g.P("body.", pth.ModelParameter, "= ctx.Param(\",", pth.Key, "\")")
 			}
+			if len(rpc.Bindings) != 0 {
+				generateBind(g, rpc)
+				g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
+				g.P("	return")
+				g.P("}")
+			}
+			if rpc.VersionField != "" {
+				g.P(
+					"if err := ",
+					runtimePackage.Ident("IfMatch"),
+					"(ctx, &body, ",
+					strconv.Quote(rpc.VersionField),
+					"); err != nil {",
+				)
+				g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
+				g.P("	return")
+				g.P("}")
+			}
 
-			g.P("var c ", contextPackage.Ident("Context"))
-			g.P("if v, ok := ctx.Get(InternalContextKey); ok {")
-			g.P("	c, _ = v.(", contextPackage.Ident("Context"), ")")
-			g.P("}")
-			g.P("if c == nil {")
-			g.P("	c = ctx")
-			g.P("}")
+			if !rpc.Async {
+				g.P("c, response := ", runtimePackage.Ident("WithResponse"), "(c)")
+			}
+
+			if rpc.Idempotent {
+				g.P("idem, err := ", runtimePackage.Ident("BeginIdempotent"), "(")
+				g.P("c,")
+				g.P("ctx,")
+				g.P("p.idempotency,")
+				g.P(fullNameConst(srv, rpc), ",")
+				g.P("&body,")
+				g.P(")")
+				g.P("if err != nil {")
+				g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
+				g.P("	return")
+				g.P("}")
+				g.P("if idem.Replayed() {")
+				g.P("	return")
+				g.P("}")
+				g.P("defer idem.End(ctx)")
+			}
+
+			if rpc.Async {
+				g.P("op, err := ", runtimePackage.Ident("StartOperation"), "(")
+				g.P("c,")
+				g.P("p.operations,")
+				g.P(fullNameConst(srv, rpc), ",")
+				g.P(
+					"func(c ",
+					contextPackage.Ident("Context"),
+					") (",
+					protoPackage.Ident("Message"),
+					", error) {",
+				)
+				g.P("return ", dispatcher(rpc), ".", rpc.Method.GoName, "(c, &body)")
+				g.P("},")
+				g.P(")")
+				g.P("if err != nil {")
+				g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
+				g.P("	return")
+				g.P("}")
+				g.P(
+					"if err := ",
+					runtimePackage.Ident("Accepted"),
+					"(ctx, p.operationsPath, op); err != nil {",
+				)
+				g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
+				g.P("}")
+				g.P("}")
+				continue
+			}
 
-			g.P("res, err := p.app.", rpc.Method.GoName, "(")
+			g.P("res, err := ", dispatcher(rpc), ".", rpc.Method.GoName, "(")
 			g.P("c,")
 			g.P("&body,")
 			g.P(")")
+			g.P("response.WriteHeader(ctx)")
 			g.P("if err != nil {")
-			g.P("ctx.Error(err)")
-			g.P("return")
-			g.P("}")
-
-			g.P("resraw, err := protomarsh.Marshal(res)")
-			g.P("if err != nil {")
-			g.P("	ctx.Error(err)")
+			g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
 			g.P("	return")
 			g.P("}")
-			g.P("ctx.Status(200)")
-			g.P("ctx.Header(\"Content-Type\", \"application/json\")")
-			g.P("_, err = ctx.Writer.Write(resraw)")
+			var status interface{} = httpPackage.Ident("StatusOK")
+			if rpc.SuccessStatus() != 200 {
+				status = rpc.SuccessStatus()
+			}
+			g.P(
+				"if err := ",
+				runtimePackage.Ident("Encode"),
+				"(ctx, codec, response.Status(",
+				status,
+				"), res, ",
+				runtimePackage.Ident("EncodeOptions"),
+				"{",
+			)
+			g.P("Compression: p.compression,")
+			if rpc.ETag {
+				g.P("ETag: true,")
+			}
+			if rpc.ETagField != "" {
+				g.P("ETagField: ", strconv.Quote(rpc.ETagField), ",")
+				g.P("Request: &body,")
+			}
+			if rpc.CacheControl != "" {
+				g.P("CacheControl: ", strconv.Quote(rpc.CacheControl), ",")
+			}
+			if len(rpc.ResponseHeaders) != 0 {
+				g.P("HeaderFields: []", runtimePackage.Ident("HeaderField"), "{")
+				for _, header := range rpc.ResponseHeaders {
+					g.P("{")
+					g.P("Field: ", strconv.Quote(string(header.Field.Desc.Name())), ",")
+					g.P("Header: ", strconv.Quote(header.Name), ",")
+					g.P("},")
+				}
+				g.P("},")
+			}
+			g.P("}); err != nil {")
+			g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
+			g.P("}")
+			g.P("}")
+		}
+
+		if srv.OperationsPath != "" {
+			g.P("// operation serves the status of an operation of an asynchronous command")
+			g.P("func (p *", ctrlName, ") operation(ctx *", ginPackage.Ident("Context"), ") {")
+			g.P("defer ", runtimePackage.Ident("Observe"), "(ctx, p.observers, ", operationsInfoVar(srv), ")()")
+			g.P("c, err := ", runtimePackage.Ident("Context"), "(ctx, p.contextFactory)")
 			g.P("if err != nil {")
-			g.P("	ctx.Error(err)")
+			g.P("	", runtimePackage.Ident("Error"), "(ctx, err)")
 			g.P("	return")
 			g.P("}")
+			g.P(runtimePackage.Ident("ServeOperation"), "(")
+			g.P("ctx,")
+			g.P("c,")
+			g.P("p.operations,")
+			// pollers meet the requirements of the rpc of the operation
+			secured := []APIPath{}
+			for _, rpc := range srv.Paths {
+				if auth := rpc.Authorization; rpc.Async && auth != nil && !auth.AllowAnonymous {
+					secured = append(secured, rpc)
+				}
+			}
+			if len(secured) == 0 {
+				g.P("nil,")
+			} else {
+				g.P("func(c ", contextPackage.Ident("Context"), ", method string) error {")
+				g.P("switch method {")
+				for _, rpc := range secured {
+					g.P("case ", fullNameConst(srv, rpc), ":")
+					g.P("return ", runtimePackage.Ident("Authorize"), "(")
+					g.P("c,")
+					g.P("p.authorizer,")
+					g.P("method,")
+					generateRequirements(g, rpc.Authorization)
+					g.P(")")
+				}
+				g.P("}")
+				g.P("return nil")
+				g.P("},")
+			}
+			g.P(")")
 			g.P("}")
+			g.P()
+		}
+		if srv.Batch != nil {
+			generateBatchHandler(g, srv, ctrlName)
 		}
 
+		optsName := srv.Service.GoName + "HTTPOptions"
+		generateHTTPOptions(g, srv, optsName)
+
 		g.P("func Register", srv.Service.GoName, "HTTPServer (")
 		g.P("grp *", ginPackage.Ident("RouterGroup"), ",")
 		g.P("srv ", intname, ",")
 		g.P(") {")
-		g.P("ctrl := ", ctrlName, "{app: srv}")
+		g.P("Register", srv.Service.GoName, "HTTPServerWithOptions(grp, srv, ", optsName, "{})")
+		g.P("}")
+		g.P()
+		g.P(
+			"// Register", srv.Service.GoName, "HTTPServerWithOptions registers the ",
+			srv.Service.GoName, " routes,",
+		)
+		g.P("// applying the middlewares of opts to each")
+		g.P("func Register", srv.Service.GoName, "HTTPServerWithOptions (")
+		g.P("grp *", ginPackage.Ident("RouterGroup"), ",")
+		g.P("srv ", intname, ",")
+		g.P("opts ", optsName, ",")
+		g.P(") {")
+		for _, bus := range []struct {
+			kind  Kind
+			field string
+		}{{KindCommand, "CommandBus"}, {KindQuery, "QueryBus"}} {
+			if len(busPaths(srv, bus.kind)) == 0 {
+				continue
+			}
+			g.P("if opts.", bus.field, " == nil {")
+			g.P("opts.", bus.field, " = New", busName(srv, bus.kind), "(srv)")
+			g.P("}")
+			if bus.kind == KindCommand {
+				g.P("if opts.Logger != nil {")
+				g.P("// a copy of the bus audits the commands, leaving the given one as is")
+				g.P("opts.", bus.field, " = &", busName(srv, bus.kind), "{")
+				g.P("app: opts.", bus.field, ".app,")
+				g.P("behaviors: append(")
+				g.P("[]", runtimePackage.Ident("Behavior"), "{", runtimePackage.Ident("AuditLog"), "(opts.Logger)},")
+				g.P("opts.", bus.field, ".behaviors...,")
+				g.P("),")
+				g.P("}")
+				g.P("}")
+			}
+		}
+		g.P("if opts.Logger != nil {")
+		g.P("opts.Observers = append(")
+		g.P("opts.Observers[:len(opts.Observers):len(opts.Observers)],")
+		g.P(runtimePackage.Ident("AccessLog"), "(opts.Logger),")
+		g.P(")")
+		g.P("}")
+		g.P("if opts.Metrics != nil {")
+		g.P("opts.Observers = append(")
+		g.P("opts.Observers[:len(opts.Observers):len(opts.Observers)],")
+		g.P("opts.Metrics(", rpcsVar(srv), "),")
+		g.P(")")
+		g.P("}")
+		g.P("ctrl := ", ctrlName, "{")
+		g.P("app: srv,")
+		if len(busPaths(srv, KindCommand)) != 0 {
+			g.P("commands: opts.CommandBus,")
+		}
+		if len(busPaths(srv, KindQuery)) != 0 {
+			g.P("queries: opts.QueryBus,")
+		}
+		g.P("authorizer: opts.Authorizer,")
+		g.P("contextFactory: opts.ContextFactory,")
+		g.P("compression: opts.Compression,")
+		g.P("idempotency: opts.Idempotency,")
+		g.P("observers: opts.Observers,")
+		if srv.OperationsPath != "" {
+			g.P("operations: opts.Operations,")
+			g.P(
+				"operationsPath: ",
+				pathPackage.Ident("Join"),
+				"(grp.BasePath(), \"",
+				srv.OperationsPath,
+				"\"),",
+			)
+		}
+		if srv.Batch != nil {
+			g.P("batch: opts.Batch,")
+			g.P("guarded: map[string]bool{")
+			for _, rpc := range srv.Paths {
+				if !rpc.Batchable() {
+					continue
+				}
+				g.P(fullNameConst(srv, rpc), ": opts.guarded(")
+				g.P(fullNameConst(srv, rpc), ",")
+				if len(rpc.Tags) == 0 {
+					g.P("nil,")
+				} else {
+					g.P(fmt.Sprintf("%#v", rpc.Tags), ",")
+				}
+				g.P("),")
+			}
+			g.P("},")
+		}
+		g.P("}")
 		for _, rpc := range srv.Paths {
 			g.P(
 				"grp.",
@@ -129,10 +470,33 @@ func GenerateHTTPServers(
 				"(\"",
 				rpc.Path,
 				"\", ",
-				"ctrl.",
-				ToPrivateName(rpc.Method.GoName),
-				")",
+				"opts.chain(",
 			)
+			g.P(kindMiddlewares(rpc.Kind), ",")
+			g.P(fullNameConst(srv, rpc), ",")
+			if len(rpc.Tags) == 0 {
+				g.P("nil,")
+			} else {
+				g.P(fmt.Sprintf("%#v", rpc.Tags), ",")
+			}
+			g.P("ctrl.", ToPrivateName(rpc.Method.GoName), ",")
+			g.P(")...)")
+		}
+		if srv.OperationsPath != "" {
+			g.P("grp.GET(\"", srv.OperationsPath, "/:id\", opts.chain(")
+			g.P("opts.Queries,")
+			g.P("\"\",")
+			g.P("nil,")
+			g.P("ctrl.operation,")
+			g.P(")...)")
+		}
+		if srv.Batch != nil {
+			g.P("grp.POST(\"", srv.Batch.Path, "\", opts.chain(")
+			g.P("opts.Commands,")
+			g.P("\"\",")
+			g.P("nil,")
+			g.P("ctrl.commandBatch,")
+			g.P(")...)")
 		}
 		g.P("}")
 	}
@@ -140,6 +504,162 @@ func GenerateHTTPServers(
 	return nil
 }
 
+// generateHTTPOptions generates the options used to attach middlewares to
+// the routes of a service
+func generateHTTPOptions(
+	g *protogen.GeneratedFile,
+	srv Server,
+	optsName string,
+) {
+	ginPackage := protogen.GoImportPath("github.com/gin-gonic/gin")
+
+	g.P("// ", optsName, " configures the middlewares of the ", srv.Service.GoName, " routes,")
+	g.P("// applied after those of the router group in the order of the fields")
+	g.P("type ", optsName, " struct {")
+	g.P("// Commands are applied to every command route")
+	g.P("Commands []", ginPackage.Ident("HandlerFunc"))
+	g.P("// Queries are applied to every query route")
+	g.P("Queries []", ginPackage.Ident("HandlerFunc"))
+	g.P("// Others are applied to every route neither a command nor a query")
+	g.P("Others []", ginPackage.Ident("HandlerFunc"))
+	g.P("// Tags are applied to the routes of the rpcs documented with a tag")
+	g.P("Tags map[string][]", ginPackage.Ident("HandlerFunc"))
+	g.P("// Methods are applied to the route of an rpc, keyed by its full name")
+	g.P("Methods map[string][]", ginPackage.Ident("HandlerFunc"))
+	if len(busPaths(srv, KindCommand)) != 0 {
+		g.P("// CommandBus dispatches the commands to the application, a bus around")
+		g.P("// the registered server without behaviors being used when it is nil")
+		g.P("CommandBus *", busName(srv, KindCommand))
+	}
+	if len(busPaths(srv, KindQuery)) != 0 {
+		g.P("// QueryBus dispatches the queries to the application, a bus around")
+		g.P("// the registered server without behaviors being used when it is nil")
+		g.P("QueryBus *", busName(srv, KindQuery))
+	}
+	g.P("// Authorizer checks the custom.authorization requirements of the rpcs,")
+	g.P("// requests to rpcs with requirements failing when it is nil")
+	g.P("Authorizer ", protogen.GoImportPath(RuntimePackage).Ident("Authorizer"))
+	g.P("// ContextFactory builds the context handed to the application,")
+	g.P("// runtime.DefaultContextFactory being used when it is nil")
+	g.P("ContextFactory ", protogen.GoImportPath(RuntimePackage).Ident("ContextFactory"))
+	g.P("// Compression compresses the response bodies when set")
+	g.P("Compression *", protogen.GoImportPath(RuntimePackage).Ident("Compression"))
+	g.P("// Idempotency stores the responses of idempotent commands, requests to")
+	g.P("// idempotent commands failing when it is nil")
+	g.P("Idempotency ", protogen.GoImportPath(RuntimePackage).Ident("IdempotencyStore"))
+	g.P("// Observers trace and measure the requests to the rpcs")
+	g.P("Observers []", protogen.GoImportPath(RuntimePackage).Ident("Observer"))
+	g.P("// Logger logs every request, and the payload of every command with its")
+	g.P("// sensitive fields masked, runtime.AuditLog being the outermost behavior")
+	g.P("// of the CommandBus, when set")
+	g.P("Logger *", protogen.GoImportPath("log/slog").Ident("Logger"))
+	g.P("// Metrics measures the rpcs with the observer it creates from ", rpcsVar(srv), ",")
+	g.P("// such as metrics.Registerer, when set")
+	g.P(
+		"Metrics func([]",
+		protogen.GoImportPath(RuntimePackage).Ident("RPCInfo"),
+		") ",
+		protogen.GoImportPath(RuntimePackage).Ident("Observer"),
+	)
+	if srv.OperationsPath != "" {
+		g.P("// Operations stores the status of asynchronous commands, requests to")
+		g.P("// asynchronous commands failing when it is nil")
+		g.P("Operations ", protogen.GoImportPath(RuntimePackage).Ident("OperationStore"))
+	}
+	if srv.Batch != nil {
+		g.P("// Batch configures how the commands of a batch are run")
+		g.P("Batch ", protogen.GoImportPath(RuntimePackage).Ident("BatchOptions"))
+	}
+	g.P("}")
+	g.P()
+	g.P("func (o *", optsName, ") chain(")
+	g.P("kind []", ginPackage.Ident("HandlerFunc"), ",")
+	g.P("method string,")
+	g.P("tags []string,")
+	g.P("handler ", ginPackage.Ident("HandlerFunc"), ",")
+	g.P(") []", ginPackage.Ident("HandlerFunc"), " {")
+	g.P("chain := append([]", ginPackage.Ident("HandlerFunc"), "{}, kind...)")
+	g.P("for _, tag := range tags {")
+	g.P("chain = append(chain, o.Tags[tag]...)")
+	g.P("}")
+	g.P("chain = append(chain, o.Methods[method]...)")
+	g.P("return append(chain, handler)")
+	g.P("}")
+	g.P()
+	if srv.Batch != nil {
+		g.P("// guarded reports whether the route of an rpc has method or tag middlewares")
+		g.P("func (o *", optsName, ") guarded(method string, tags []string) bool {")
+		g.P("if len(o.Methods[method]) != 0 {")
+		g.P("return true")
+		g.P("}")
+		g.P("for _, tag := range tags {")
+		g.P("if len(o.Tags[tag]) != 0 {")
+		g.P("return true")
+		g.P("}")
+		g.P("}")
+		g.P("return false")
+		g.P("}")
+		g.P()
+	}
+}
+
+// generateRequirements generates the runtime.Requirements argument of the
+// authorization of an rpc
+func generateRequirements(
+	g *protogen.GeneratedFile,
+	auth *annotations.Authorization,
+) {
+	g.P(protogen.GoImportPath(RuntimePackage).Ident("Requirements"), "{")
+	if len(auth.Scopes) != 0 {
+		g.P("Scopes: ", fmt.Sprintf("%#v", auth.Scopes), ",")
+	}
+	if len(auth.Roles) != 0 {
+		g.P("Roles: ", fmt.Sprintf("%#v", auth.Roles), ",")
+	}
+	if len(auth.Permissions) != 0 {
+		g.P("Permissions: ", fmt.Sprintf("%#v", auth.Permissions), ",")
+	}
+	g.P("},")
+}
+
+// rpcsVar is the name of the variable listing the rpcs of a service
+func rpcsVar(srv Server) string {
+	return srv.Service.GoName + "RPCs"
+}
+
+// operationsInfoVar is the name of the variable describing the operations
+// route of a service to observers
+func operationsInfoVar(srv Server) string {
+	return srv.Service.GoName + "OperationsRPC"
+}
+
+// batchInfoVar is the name of the variable describing the batch route of a
+// service to observers
+func batchInfoVar(srv Server) string {
+	return srv.Service.GoName + "BatchRPC"
+}
+
+// rpcInfoVar is the name of the variable describing an rpc to observers
+func rpcInfoVar(srv Server, rpc APIPath) string {
+	return srv.Service.GoName + "_" + rpc.Method.GoName + "_RPC"
+}
+
+// fullNameConst is the name of the constant holding the full name of an rpc
+func fullNameConst(srv Server, rpc APIPath) string {
+	return srv.Service.GoName + "_" + rpc.Method.GoName + "_FullName"
+}
+
+// kindMiddlewares is the options field holding the middlewares of a kind
+func kindMiddlewares(kind Kind) string {
+	switch kind {
+	case KindCommand:
+		return "opts.Commands"
+	case KindQuery:
+		return "opts.Queries"
+	}
+	return "opts.Others"
+}
+
 type info struct {
 	Title   string `json:"title"`
 	Version string `json:"version"`
@@ -158,6 +678,7 @@ func GenerateOpenAPI(
 	gjson *protogen.GeneratedFile,
 	file *protogen.File,
 ) error {
+	schemes := SecuritySchemes(file)
 	g.P("openapi: 3.0.3")
 	g.P("info:")
 	g.P("  title: ", file.Desc.Package())
@@ -173,28 +694,86 @@ func GenerateOpenAPI(
 					g.P("        - ", tag)
 				}
 			}
-			g.P("      summary: ", api.Summary)         // TODO: escaping
-			g.P("      description: ", api.Description) // TODO: escaping
-			g.P("      requestBody:")
-			g.P("        description: ", api.Method.Input.GoIdent.GoName)
-			g.P("        content:")
-			g.P("          application/json:")
-			g.P("            schema:")
-			g.P("              $ref: '#/components/schemas/", api.Method.Input.GoIdent.GoName, "'")
-			g.P("        required: true")
+			g.P("      summary: ", yamlString(api.Summary))
+			g.P("      description: ", yamlString(api.Description))
+			generateOpenAPISecurity(g, api, schemes)
+			generateOpenAPIParameters(g, api)
+			if api.HasBody() {
+				g.P("      requestBody:")
+				g.P("        description: ", api.Method.Input.GoIdent.GoName)
+				g.P("        content:")
+				for _, media := range MediaTypes {
+					g.P("          ", media, ":")
+					g.P("            schema:")
+					g.P("              $ref: '#/components/schemas/", requestBodySchema(api), "'")
+				}
+				g.P("        required: true")
+				if api.MaxBodyBytes > 0 {
+					g.P("        x-max-body-size: ", api.MaxBodyBytes)
+				}
+			}
 			g.P("      responses:")
-			g.P("        '200':")
-			g.P("          description: ", api.Method.Output.GoIdent.GoName)
-			g.P("          content: ")
-			g.P("            application/json:")
-			g.P("              schema:")
-			g.P(
-				"                $ref: '#/components/schemas/",
-				api.Method.Output.GoIdent.GoName,
-				"'",
-			)
+			if api.Async {
+				generateOpenAPIAccepted(g, svc)
+			} else {
+				g.P("        '", api.SuccessStatus(), "':")
+				g.P("          description: ", api.Method.Output.GoIdent.GoName)
+				generateOpenAPIResponseHeaders(g, api)
+				if api.SuccessStatus() != 204 && api.SuccessStatus() != 205 {
+					g.P("          content: ")
+					for _, media := range MediaTypes {
+						g.P("            ", media, ":")
+						g.P("              schema:")
+						g.P(
+							"                $ref: '#/components/schemas/",
+							responseBodySchema(api),
+							"'",
+						)
+					}
+				}
+			}
+			if api.ETag && api.HTTPMethod == "GET" {
+				g.P("        '304':")
+				g.P("          description: The response matches the If-None-Match header")
+			}
+			if auth := api.Authorization; auth != nil && !auth.AllowAnonymous {
+				g.P("        '401':")
+				g.P("          description: The caller is not authenticated")
+				g.P("        '403':")
+				g.P("          description: The caller does not meet the requirements of the operation")
+			}
+			if !api.Async {
+				g.P("        '406':")
+				g.P("          description: None of the accepted media types is supported")
+			}
+			if api.HasBody() {
+				g.P("        '415':")
+				g.P("          description: The media type of the request body is not supported")
+			}
+			if api.Idempotent {
+				g.P("        '400':")
+				g.P("          description: The Idempotency-Key header is missing")
+				g.P("        '409':")
+				g.P("          description: A request with the same Idempotency-Key is in flight")
+				g.P("        '422':")
+				g.P("          description: The Idempotency-Key was used with a different payload")
+			}
+			switch {
+			case api.VersionField != "":
+				g.P("        '412':")
+				g.P("          description: The If-Match header does not match the current version")
+			case api.ETag && api.HTTPMethod != "GET":
+				g.P("        '412':")
+				g.P("          description: The response matches the If-None-Match header")
+			}
+			if api.HasBody() && api.MaxBodyBytes > 0 {
+				g.P("        '413':")
+				g.P("          description: The request body is too large")
+			}
 
 		}
+		generateOpenAPIOperationsPath(g, svc)
+		generateOpenAPIBatchPath(g, svc)
 	}
 
 	g.P("components:")
@@ -219,8 +798,27 @@ func GenerateOpenAPI(
 				return err
 			}
 
+			if err := generateOpenAPIRequestBodySchema(
+				g,
+				schemas,
+				api,
+			); err != nil {
+				return err
+			}
+
+			if err := generateOpenAPIResponseBodySchema(
+				g,
+				schemas,
+				api,
+			); err != nil {
+				return err
+			}
+
 		}
+		generateOpenAPIOperationSchema(g, svc)
+		generateOpenAPIBatchSchemas(g, svc)
 	}
+	generateOpenAPISecuritySchemes(g, schemes)
 
 	bytes, err := g.Content()
 	if err != nil {
@@ -236,6 +834,134 @@ func GenerateOpenAPI(
 	return nil
 }
 
+// requestParameter is a request header, cookie or query parameter documented
+// on an operation
+type requestParameter struct {
+	Name string
+	// In is header, cookie or query
+	In          string
+	Required    bool
+	Description string
+	Type        string
+	Format      string
+	// List is set for the repeated query parameters of lists
+	List bool
+}
+
+// requestParameters lists the request headers, cookies and query parameters
+// an operation reads
+func requestParameters(api APIPath) []requestParameter {
+	params := []requestParameter{}
+	if api.Idempotent {
+		params = append(params, requestParameter{
+			Name:        "Idempotency-Key",
+			In:          InHeader,
+			Required:    true,
+			Description: "Identifies the command so that retries replay its response",
+			Type:        "string",
+		})
+	}
+	if api.VersionField != "" {
+		params = append(params, requestParameter{
+			Name:        "If-Match",
+			In:          InHeader,
+			Description: "Version the command expects, failing with 412 when it is not current",
+			Type:        "string",
+		})
+	}
+	if api.ETag {
+		description := "ETags of cached responses, answered with 304 when one matches"
+		if api.HTTPMethod != "GET" {
+			description = "ETags of cached responses, failing with 412 when one matches"
+		}
+		params = append(params, requestParameter{
+			Name:        "If-None-Match",
+			In:          InHeader,
+			Description: description,
+			Type:        "string",
+		})
+	}
+	for _, binding := range api.Bindings {
+		typ, format := scalarSchema(binding.Field.Desc.Kind())
+		params = append(params, requestParameter{
+			Name:        binding.Name,
+			In:          binding.In,
+			Required:    binding.Required,
+			Description: fieldDescription(binding.Field),
+			Type:        typ,
+			Format:      format,
+			List:        binding.Field.Desc.IsList(),
+		})
+	}
+	return params
+}
+
+// generateOpenAPIParameters generates the header, cookie and query parameters
+// of an operation
+func generateOpenAPIParameters(g *protogen.GeneratedFile, api APIPath) {
+	params := requestParameters(api)
+	if len(params) == 0 {
+		return
+	}
+	g.P("      parameters:")
+	for _, param := range params {
+		g.P("        - name: ", param.Name)
+		g.P("          in: ", param.In)
+		g.P("          required: ", param.Required)
+		g.P("          description: ", yamlString(param.Description))
+		g.P("          schema:")
+		indent := "            "
+		if param.List {
+			g.P(indent, "type: array")
+			g.P(indent, "items:")
+			indent += "  "
+		}
+		g.P(indent, "type: ", param.Type)
+		if param.Format != "" {
+			g.P(indent, "format: ", param.Format)
+		}
+	}
+}
+
+// generateOpenAPIResponseHeaders generates the headers of the successful
+// response of an operation
+func generateOpenAPIResponseHeaders(g *protogen.GeneratedFile, api APIPath) {
+	if !api.ETag && len(api.ResponseHeaders) == 0 {
+		return
+	}
+	g.P("          headers:")
+	if api.ETag {
+		g.P("            ETag:")
+		g.P("              description: Version of the response")
+		g.P("              schema:")
+		g.P("                type: string")
+	}
+	if api.ETag && api.CacheControl != "" {
+		g.P("            Cache-Control:")
+		g.P("              description: Caching directives of the response")
+		g.P("              schema:")
+		g.P("                type: string")
+		g.P("                example: ", yamlString(api.CacheControl))
+	}
+	for _, header := range api.ResponseHeaders {
+		typ, format := scalarSchema(header.Field.Desc.Kind())
+		g.P("            ", header.Name, ":")
+		g.P("              description: ", yamlString(fieldDescription(header.Field)))
+		g.P("              schema:")
+		g.P("                type: ", typ)
+		if format != "" {
+			g.P("                format: ", format)
+		}
+	}
+}
+
+// yamlString quotes a string so that it can be written as a yaml scalar, json
+// strings being valid double quoted yaml
+func yamlString(in string) string {
+	raw, _ := json.Marshal(in)
+	return string(raw)
+}
+
 func ToPrivateName(in string) (out string) {
 	inr := []rune(in)
 	inr[0] = unicode.ToLower(inr[0])
@@ -247,16 +973,97 @@ func generateOpenAPIComponentSchema(
 	g *protogen.GeneratedFile,
 	s map[string]struct{},
 	m *protogen.Message,
+) error {
+	return generateOpenAPIMessageSchema(
+		g,
+		s,
+		m.GoIdent.GoName,
+		m,
+		func(*protogen.Field) bool { return false },
+	)
+}
+
+// requestBodySchema is the name of the schema of the request bodies of an
+// rpc, left without the fields bound to headers and cookies
+func requestBodySchema(api APIPath) string {
+	if len(api.Bindings) == 0 {
+		return api.Method.Input.GoIdent.GoName
+	}
+	return api.Method.Input.GoIdent.GoName + "Body"
+}
+
+// generateOpenAPIRequestBodySchema generates the schema of the request
+// bodies of an rpc binding fields to headers and cookies
+func generateOpenAPIRequestBodySchema(
+	g *protogen.GeneratedFile,
+	s map[string]struct{},
+	api APIPath,
+) error {
+	if len(api.Bindings) == 0 || !api.HasBody() {
+		return nil
+	}
+	return generateOpenAPIMessageSchema(
+		g,
+		s,
+		requestBodySchema(api),
+		api.Method.Input,
+		isBound,
+	)
+}
+
+// responseBodySchema is the name of the schema of the response bodies of an
+// rpc, left without the fields mapped to headers
+func responseBodySchema(api APIPath) string {
+	if len(api.ResponseHeaders) == 0 {
+		return api.Method.Output.GoIdent.GoName
+	}
+	return api.Method.Output.GoIdent.GoName + "Body"
+}
+
+// generateOpenAPIResponseBodySchema generates the schema of the response
+// bodies of an rpc mapping fields to headers
+func generateOpenAPIResponseBodySchema(
+	g *protogen.GeneratedFile,
+	s map[string]struct{},
+	api APIPath,
+) error {
+	if len(api.ResponseHeaders) == 0 {
+		return nil
+	}
+	return generateOpenAPIMessageSchema(
+		g,
+		s,
+		responseBodySchema(api),
+		api.Method.Output,
+		isResponseHeader,
+	)
+}
+
+// generateOpenAPIMessageSchema generates the schema of a message under name,
+// leaving out the fields skip reports
+func generateOpenAPIMessageSchema(
+	g *protogen.GeneratedFile,
+	s map[string]struct{},
+	name string,
+	m *protogen.Message,
+	skip func(*protogen.Field) bool,
 ) error {
 	foundMessages := []*protogen.Message{}
-	if _, ok := s[m.GoIdent.GoName]; !ok {
-		s[m.GoIdent.GoName] = struct{}{}
-		g.P("    ", m.GoIdent.GoName, ":")
+	if _, ok := s[name]; !ok {
+		s[name] = struct{}{}
+		g.P("    ", name, ":")
 		g.P("      type: object")
 		g.P("      properties:")
 		for _, fld := range m.Fields {
+			if skip(fld) {
+				continue
+			}
 			field := fld
 			g.P("        ", field.Desc.JSONName(), ":")
+			if IsSensitive(field) {
+				g.P("          writeOnly: true")
+				g.P("          x-sensitive: true")
+			}
 
 			prfx := ""
 			if field.Desc.IsMap() {
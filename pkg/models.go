@@ -1,23 +1,93 @@
 package pkg
 
-import "google.golang.org/protobuf/compiler/protogen"
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"techunicorn.com/protoc-gen-gocqrshttp/custom/annotations"
+)
+
+// RuntimePackage is the import path of the support library of the
+// generated code
+const RuntimePackage = "techunicorn.com/protoc-gen-gocqrshttp/runtime"
+
+// MediaTypes are the media types supported by the generated handlers
+var MediaTypes = []string{"application/json", "application/x-protobuf"}
+
+// RuntimeVersion is the version of the runtime package the generated code
+// requires
+const RuntimeVersion = 1
 
 type Server struct {
 	Service *protogen.Service
 	Paths   []APIPath
+	// OperationsPath is the route of the operations of the asynchronous
+	// commands of the service, empty when it has none
+	OperationsPath string
+	// Batch is the route of the batches of commands of the service, nil
+	// when they are not enabled
+	Batch *APIPath
 }
 
 type APIPath struct {
 	Method          *protogen.Method
+	Kind            Kind
 	Tags            []string
 	Description     string
 	Summary         string
 	Path            string
 	HTTPMethod      string
+	Authorization   *annotations.Authorization
+	MaxBodyBytes    int64
+	Idempotent      bool
+	Async           bool
+	Emits           []*protogen.Message
+	ETag            bool
+	ETagField       string
+	CacheControl    string
+	VersionField    string
+	Bindings        []Binding
+	Status          int
+	ResponseHeaders []ResponseHeader
 	PathParameters  []Parameter
 	QueryParameters []Parameter
 }
 
+// Binding binds a field of the input of an rpc to a request header, cookie
+// or query parameter
+type Binding struct {
+	Field *protogen.Field
+	// In is header, cookie or query
+	In       string
+	Name     string
+	Required bool
+}
+
+// ResponseHeader maps a field of the output of an rpc to a response header
+type ResponseHeader struct {
+	Field *protogen.Field
+	Name  string
+}
+
+// SuccessStatus is the status of the successful responses of an rpc
+func (a APIPath) SuccessStatus() int {
+	if a.Status == 0 {
+		return 200
+	}
+	return a.Status
+}
+
+// HasBody reports whether the requests of an rpc carry its input in their
+// body, queries being read from their query parameters instead
+func (a APIPath) HasBody() bool {
+	return a.HTTPMethod != "GET"
+}
+
+// Sources of the bindings of input fields
+const (
+	InHeader = "header"
+	InCookie = "cookie"
+	InQuery  = "query"
+)
+
 type Parameter struct {
 	ModelParameter string
 	Key            string
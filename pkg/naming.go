@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Kind is the CQRS kind of an operation
+type Kind int
+
+const (
+	KindCommand Kind = iota + 1
+	KindQuery
+	KindOther
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindCommand:
+		return "command"
+	case KindQuery:
+		return "query"
+	case KindOther:
+		return "other"
+	}
+	return "unknown"
+}
+
+// Plural is the plural of the name of a kind
+func (k Kind) Plural() string {
+	switch k {
+	case KindCommand:
+		return "commands"
+	case KindQuery:
+		return "queries"
+	case KindOther:
+		return "others"
+	}
+	return "unknown"
+}
+
+// Casing is the casing applied to derived path segments
+type Casing string
+
+const (
+	CamelCase Casing = "camel"
+	KebabCase Casing = "kebab"
+	SnakeCase Casing = "snake"
+)
+
+// ParseCasing validates a casing name
+func ParseCasing(in string) (Casing, error) {
+	switch c := Casing(in); c {
+	case CamelCase, KebabCase, SnakeCase:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown path casing %q, expected camel, kebab or snake", in)
+}
+
+// Format applies the casing to a go style name
+func (c Casing) Format(name string) string {
+	switch c {
+	case KebabCase:
+		return strings.ToLower(strings.Join(splitWords(name), "-"))
+	case SnakeCase:
+		return strings.ToLower(strings.Join(splitWords(name), "_"))
+	}
+	return ToPrivateName(name)
+}
+
+// Category maps input messages with a suffix to a route prefix, for
+// operations that are neither commands nor queries
+type Category struct {
+	Suffix string
+	Prefix string
+}
+
+// NamingRules configures how operations are classified and routed from
+// their input message names
+type NamingRules struct {
+	CommandSuffixes []string
+	QuerySuffixes   []string
+	EventSuffixes   []string
+	Categories      []Category
+	CommandPrefix   string
+	QueryPrefix     string
+	Casing          Casing
+}
+
+// Classify resolves the kind, category and base name of an input message
+// from its suffix, reporting false if no suffix matches
+func (n NamingRules) Classify(
+	name string,
+) (kind Kind, category string, base string, ok bool) {
+	for _, sfx := range n.CommandSuffixes {
+		if base, ok = trimSuffix(name, sfx); ok {
+			return KindCommand, n.CommandPrefix, base, true
+		}
+	}
+	for _, sfx := range n.QuerySuffixes {
+		if base, ok = trimSuffix(name, sfx); ok {
+			return KindQuery, n.QueryPrefix, base, true
+		}
+	}
+	for _, cat := range n.Categories {
+		if base, ok = trimSuffix(name, cat.Suffix); ok {
+			return KindOther, cat.Prefix, base, true
+		}
+	}
+	return 0, "", name, false
+}
+
+// Route builds the path of an operation from its route prefix and segment
+func (n NamingRules) Route(prefix string, segment string) string {
+	return "/" + strings.Trim(prefix, "/") + "/" + strings.Trim(segment, "/")
+}
+
+// IsEvent reports whether a message name ends with an event suffix
+func (n NamingRules) IsEvent(name string) bool {
+	for _, suffix := range n.EventSuffixes {
+		if _, ok := trimSuffix(name, suffix); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchRoute is the route of the batches of commands of a service
+func (n NamingRules) BatchRoute() string {
+	return "/" + strings.Trim(n.CommandPrefix, "/") + "/-/batch"
+}
+
+// trimSuffix removes suffix from name, reporting whether name ends with
+// suffix and has anything left once it is removed
+func trimSuffix(name string, suffix string) (string, bool) {
+	trimmed := strings.TrimSuffix(name, suffix)
+	return trimmed, trimmed != name && trimmed != ""
+}
+
+// splitWords splits a go style name into words, keeping acronyms together
+func splitWords(name string) []string {
+	runes := []rune(name)
+	words := []string{}
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if cur == '_' || cur == '-' {
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if unicode.IsUpper(cur) && (unicode.IsLower(prev) ||
+			unicode.IsDigit(prev) ||
+			(unicode.IsUpper(prev) && unicode.IsLower(next))) {
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
@@ -0,0 +1,80 @@
+package pkg
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	rules := NamingRules{
+		CommandSuffixes: []string{"Command", "Cmd"},
+		QuerySuffixes:   []string{"Query"},
+		Categories:      []Category{{Suffix: "Request", Prefix: "rpc"}},
+		CommandPrefix:   "commands",
+		QueryPrefix:     "queries",
+	}
+	tests := []struct {
+		input    string
+		kind     Kind
+		category string
+		base     string
+		ok       bool
+	}{
+		{input: "PlaceOrderCommand", kind: KindCommand, category: "commands", base: "PlaceOrder", ok: true},
+		{input: "PlaceOrderCmd", kind: KindCommand, category: "commands", base: "PlaceOrder", ok: true},
+		{input: "ListOrdersQuery", kind: KindQuery, category: "queries", base: "ListOrders", ok: true},
+		{input: "PingRequest", kind: KindOther, category: "rpc", base: "Ping", ok: true},
+		{input: "CommandeerOrder", base: "CommandeerOrder"},
+		{input: "Command", base: "Command"},
+		{input: "Order", base: "Order"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			kind, category, base, ok := rules.Classify(tt.input)
+			if kind != tt.kind || category != tt.category || base != tt.base || ok != tt.ok {
+				t.Errorf(
+					"Classify = %v, %q, %q, %v, want %v, %q, %q, %v",
+					kind, category, base, ok,
+					tt.kind, tt.category, tt.base, tt.ok,
+				)
+			}
+		})
+	}
+}
+
+func TestCasingFormat(t *testing.T) {
+	tests := []struct {
+		casing Casing
+		name   string
+		want   string
+	}{
+		{casing: CamelCase, name: "PlaceOrder", want: "placeOrder"},
+		{casing: KebabCase, name: "PlaceOrder", want: "place-order"},
+		{casing: SnakeCase, name: "PlaceOrder", want: "place_order"},
+		{casing: KebabCase, name: "GetHTTPRoute", want: "get-http-route"},
+		{casing: SnakeCase, name: "ImportV2Orders", want: "import_v2_orders"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.casing)+" "+tt.name, func(t *testing.T) {
+			if got := tt.casing.Format(tt.name); got != tt.want {
+				t.Errorf("Format = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := ParseCasing("pascal"); err == nil {
+		t.Error("unknown casing parsed")
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	rules := NamingRules{
+		CommandPrefix: "/commands/",
+		EventSuffixes: []string{"Event"},
+	}
+	if got := rules.Route("/commands/", "/placeOrder"); got != "/commands/placeOrder" {
+		t.Errorf("Route = %q", got)
+	}
+	if got := rules.BatchRoute(); got != "/commands/-/batch" {
+		t.Errorf("BatchRoute = %q", got)
+	}
+	if !rules.IsEvent("OrderPlacedEvent") || rules.IsEvent("Event") || rules.IsEvent("OrderPlaced") {
+		t.Error("events misclassified")
+	}
+}
@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// operationSchema is the name of the schema of the operations of a service
+func operationSchema(srv Server) string {
+	return srv.Service.GoName + "Operation"
+}
+
+// generateOpenAPIAccepted generates the response of an asynchronous command
+func generateOpenAPIAccepted(g *protogen.GeneratedFile, srv Server) {
+	g.P("        '202':")
+	g.P("          description: The command runs in the background, poll the operation at its Location for the result")
+	g.P("          headers:")
+	g.P("            Location:")
+	g.P("              description: Route of the operation of the command")
+	g.P("              schema:")
+	g.P("                type: string")
+	g.P("          content:")
+	g.P("            application/json:")
+	g.P("              schema:")
+	g.P("                $ref: '#/components/schemas/", operationSchema(srv), "'")
+}
+
+// generateOpenAPIOperationsPath generates the route polled for the status
+// of the asynchronous commands of a service
+func generateOpenAPIOperationsPath(g *protogen.GeneratedFile, srv Server) {
+	if srv.OperationsPath == "" {
+		return
+	}
+	g.P("  ", srv.OperationsPath, "/{id}:")
+	g.P("    get:")
+	g.P("      summary: ", yamlString("Get an operation of "+srv.Service.GoName))
+	g.P("      description: ", yamlString(
+		"Polls the status of an asynchronous command until it is no longer "+
+			"pending, its result or error being set once it succeeded or failed",
+	))
+	g.P("      parameters:")
+	g.P("        - name: id")
+	g.P("          in: path")
+	g.P("          required: true")
+	g.P("          schema:")
+	g.P("            type: string")
+	g.P("      responses:")
+	g.P("        '200':")
+	g.P("          description: ", operationSchema(srv))
+	g.P("          headers:")
+	g.P("            Retry-After:")
+	g.P("              description: Seconds to wait before polling a pending operation again")
+	g.P("              schema:")
+	g.P("                type: integer")
+	g.P("          content:")
+	g.P("            application/json:")
+	g.P("              schema:")
+	g.P("                $ref: '#/components/schemas/", operationSchema(srv), "'")
+	g.P("        '404':")
+	g.P("          description: The operation is unknown or expired")
+}
+
+// generateOpenAPIOperationSchema generates the schema of the operations of
+// a service, its result being the output of one of its asynchronous commands
+func generateOpenAPIOperationSchema(g *protogen.GeneratedFile, srv Server) {
+	if srv.OperationsPath == "" {
+		return
+	}
+	results := []string{}
+	seen := map[string]struct{}{}
+	for _, api := range srv.Paths {
+		output := api.Method.Output.GoIdent.GoName
+		if _, ok := seen[output]; ok || !api.Async {
+			continue
+		}
+		seen[output] = struct{}{}
+		results = append(results, output)
+	}
+
+	g.P("    ", operationSchema(srv), ":")
+	g.P("      type: object")
+	g.P("      properties:")
+	g.P("        id:")
+	g.P("          type: string")
+	g.P("        method:")
+	g.P("          type: string")
+	g.P("          description: Full name of the rpc of the command")
+	g.P("        state:")
+	g.P("          type: string")
+	g.P("          enum: [pending, succeeded, failed]")
+	g.P("        result:")
+	g.P("          oneOf:")
+	for _, result := range results {
+		g.P("            - $ref: '#/components/schemas/", result, "'")
+	}
+	g.P("        error:")
+	g.P("          type: object")
+	g.P("          properties:")
+	g.P("            status:")
+	g.P("              type: integer")
+	g.P("            message:")
+	g.P("              type: string")
+	g.P("        created:")
+	g.P("          type: string")
+	g.P("          format: date-time")
+	g.P("        updated:")
+	g.P("          type: string")
+	g.P("          format: date-time")
+	g.P("      required: [id, method, state]")
+}
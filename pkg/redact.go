@@ -0,0 +1,177 @@
+package pkg
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// IsSensitive reports whether a field is marked sensitive with the
+// custom.field option
+func IsSensitive(field *protogen.Field) bool {
+	return fieldOption(field).GetSensitive()
+}
+
+// Redactions finds the messages with a generated Redact method, those with
+// sensitive fields or fields of such messages
+type Redactions struct {
+	redacted  map[protoreflect.FullName]bool
+	generated map[protoreflect.FullName]bool
+}
+
+// FindRedactions finds the messages of every file of a request with a
+// generated Redact method
+func FindRedactions(files []*protogen.File) Redactions {
+	r := Redactions{
+		redacted:  map[protoreflect.FullName]bool{},
+		generated: map[protoreflect.FullName]bool{},
+	}
+	msgs := []*protogen.Message{}
+	var add func(file *protogen.File, nested []*protogen.Message)
+	add = func(file *protogen.File, nested []*protogen.Message) {
+		for _, msg := range nested {
+			msgs = append(msgs, msg)
+			r.generated[msg.Desc.FullName()] = file.Generate
+			add(file, msg.Messages)
+		}
+	}
+	for _, file := range files {
+		add(file, file.Messages)
+	}
+
+	// messages are redacted as soon as one of their fields is, until no
+	// more are found, which accounts for recursive messages
+	for changed := true; changed; {
+		changed = false
+		for _, msg := range msgs {
+			if r.redacted[msg.Desc.FullName()] {
+				continue
+			}
+			for _, field := range msg.Fields {
+				if IsSensitive(field) || r.Redacted(redactedMessage(field)) {
+					r.redacted[msg.Desc.FullName()] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return r
+}
+
+// Redacted reports whether msg has a generated Redact method
+func (r Redactions) Redacted(msg *protogen.Message) bool {
+	return msg != nil && !msg.Desc.IsMapEntry() && r.redacted[msg.Desc.FullName()]
+}
+
+// redactedMessage is the message a field holds, the value of map fields,
+// nil for fields of other kinds
+func redactedMessage(field *protogen.Field) *protogen.Message {
+	if field.Desc.IsMap() {
+		field = field.Message.Fields[1]
+	}
+	return field.Message
+}
+
+// HasRedactions reports whether any message of a file has a generated
+// Redact method
+func (r Redactions) HasRedactions(file *protogen.File) bool {
+	return len(r.fileMessages(file.Messages)) != 0
+}
+
+// fileMessages lists the messages of msgs, nested ones included, with a
+// generated Redact method
+func (r Redactions) fileMessages(msgs []*protogen.Message) []*protogen.Message {
+	found := []*protogen.Message{}
+	for _, msg := range msgs {
+		if r.Redacted(msg) {
+			found = append(found, msg)
+		}
+		found = append(found, r.fileMessages(msg.Messages)...)
+	}
+	return found
+}
+
+// GenerateRedactions generates the Redact methods of the messages of a file,
+// masking their sensitive fields and those of the messages they hold
+func GenerateRedactions(
+	r Redactions,
+	file *protogen.File,
+	g *protogen.GeneratedFile,
+) {
+	for _, msg := range r.fileMessages(file.Messages) {
+		g.P("// Redact masks the sensitive fields of x, and of the messages it holds")
+		g.P("func (x *", msg.GoIdent, ") Redact() {")
+		g.P("if x == nil {")
+		g.P("return")
+		g.P("}")
+		for _, field := range msg.Fields {
+			if !IsSensitive(field) && !r.Redacted(redactedMessage(field)) {
+				continue
+			}
+			if field.Oneof == nil || field.Oneof.Desc.IsSynthetic() {
+				generateFieldRedaction(g, r, field, "x."+field.GoName)
+				continue
+			}
+			g.P("if v, ok := x.", field.Oneof.GoName, ".(*", field.GoIdent, "); ok {")
+			generateFieldRedaction(g, r, field, "v."+field.GoName)
+			g.P("}")
+		}
+		g.P("}")
+		g.P()
+	}
+}
+
+// generateFieldRedaction generates the masking of the sensitive field held
+// by target, or the redaction of the messages it holds
+func generateFieldRedaction(
+	g *protogen.GeneratedFile,
+	r Redactions,
+	field *protogen.Field,
+	target string,
+) {
+	runtimePackage := protogen.GoImportPath(RuntimePackage)
+
+	if !IsSensitive(field) {
+		redact := func(value string) {
+			msg := redactedMessage(field)
+			if r.generated[msg.Desc.FullName()] {
+				g.P(value, ".Redact()")
+			} else {
+				g.P(runtimePackage.Ident("RedactField"), "(", value, ")")
+			}
+		}
+		if field.Desc.IsMap() || field.Desc.IsList() {
+			g.P("for _, v := range ", target, " {")
+			redact("v")
+			g.P("}")
+		} else {
+			redact(target)
+		}
+		return
+	}
+
+	pointer := field.Desc.HasPresence() &&
+		field.Desc.Kind() != protoreflect.MessageKind &&
+		(field.Oneof == nil || field.Oneof.Desc.IsSynthetic())
+	switch {
+	case field.Desc.IsMap() || field.Desc.IsList(),
+		field.Desc.Kind() == protoreflect.MessageKind,
+		field.Desc.Kind() == protoreflect.GroupKind,
+		field.Desc.Kind() == protoreflect.BytesKind:
+		g.P(target, " = nil")
+	case field.Desc.Kind() == protoreflect.StringKind && pointer:
+		g.P("if ", target, " != nil {")
+		g.P("*", target, " = ", runtimePackage.Ident("RedactedString"))
+		g.P("}")
+	case field.Desc.Kind() == protoreflect.StringKind:
+		g.P("if ", target, " != \"\" {")
+		g.P(target, " = ", runtimePackage.Ident("RedactedString"))
+		g.P("}")
+	case pointer:
+		g.P(target, " = nil")
+	case field.Desc.Kind() == protoreflect.BoolKind:
+		g.P(target, " = false")
+	default:
+		g.P(target, " = 0")
+	}
+}
@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// Route is a registered http route
+type Route struct {
+	Service *protogen.Service
+	API     APIPath
+	// Builtin names the routes of a service rather than of one of its rpcs,
+	// batch or operations
+	Builtin string
+}
+
+// Builtin routes of a service
+const (
+	BatchRoute      = "batch"
+	OperationsRoute = "operations"
+)
+
+// Name is the full name of the rpc of a route, or of its service followed by
+// the name of the route for the builtin routes of the service
+func (r Route) Name() string {
+	if r.API.Method == nil {
+		return string(r.Service.Desc.FullName()) + " " + r.Builtin
+	}
+	return string(r.API.Method.Desc.FullName())
+}
+
+// RouteTable tracks the routes of every service across every file of a
+// request, detecting routes that gin would refuse to register together
+type RouteTable struct {
+	Routes []Route
+}
+
+// Add registers the route of an api, returning the route it conflicts with
+// if any. Conflicting routes either share the same method and path or
+// overlap through wildcard segments.
+func (t *RouteTable) Add(srv *protogen.Service, api APIPath) *Route {
+	return t.add(Route{Service: srv, API: api})
+}
+
+// AddBuiltin registers the builtin route of a service, returning the route
+// it conflicts with if any
+func (t *RouteTable) AddBuiltin(
+	srv *protogen.Service,
+	builtin string,
+	api APIPath,
+) *Route {
+	return t.add(Route{Service: srv, API: api, Builtin: builtin})
+}
+
+func (t *RouteTable) add(route Route) *Route {
+	for i, rt := range t.Routes {
+		if rt.API.HTTPMethod == route.API.HTTPMethod &&
+			pathsOverlap(rt.API.Path, route.API.Path) {
+			return &t.Routes[i]
+		}
+	}
+	t.Routes = append(t.Routes, route)
+	return nil
+}
+
+// pathsOverlap reports whether two paths can match the same request, or
+// use wildcards that gin cannot register together. Wildcards may start in the
+// middle of a segment, such as /files/report:format, and match its rest.
+func pathsOverlap(a string, b string) bool {
+	as := strings.Split(strings.Trim(a, "/"), "/")
+	bs := strings.Split(strings.Trim(b, "/"), "/")
+	prefix := true
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		aStatic, aWildcard := splitWildcard(as[i])
+		bStatic, bWildcard := splitWildcard(bs[i])
+		if !strings.HasPrefix(aStatic, bStatic) && !strings.HasPrefix(bStatic, aStatic) {
+			return false
+		}
+		if isCatchAll(aWildcard) || isCatchAll(bWildcard) {
+			return true
+		}
+		switch {
+		case as[i] == bs[i]:
+			continue
+		case aWildcard == "" && bWildcard == "":
+			return false
+		case aWildcard != "" && bWildcard != "":
+			// gin requires wildcards sharing a prefix to share a name
+			if prefix && aStatic == bStatic {
+				return true
+			}
+		case aWildcard != "" && len(bStatic) <= len(aStatic),
+			bWildcard != "" && len(aStatic) <= len(bStatic):
+			// parameters match at least one character
+			return false
+		}
+		prefix = false
+	}
+	return len(as) == len(bs)
+}
+
+// splitWildcard splits a segment into its static prefix and the wildcard
+// matching its rest, if any
+func splitWildcard(segment string) (string, string) {
+	i := strings.IndexAny(segment, ":*")
+	if i < 0 {
+		return segment, ""
+	}
+	return segment[:i], segment[i:]
+}
+
+func isCatchAll(wildcard string) bool {
+	return strings.HasPrefix(wildcard, "*")
+}
+
+type manifestRoute struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Kind    string `json:"kind"`
+	Service string `json:"service"`
+	RPC     string `json:"rpc"`
+	Input   string `json:"input,omitempty"`
+	Output  string `json:"output,omitempty"`
+	Source  string `json:"source"`
+}
+
+// GenerateRouteManifest writes the route table as a json manifest
+func GenerateRouteManifest(
+	t *RouteTable,
+	g *protogen.GeneratedFile,
+) error {
+	routes := make([]manifestRoute, 0, len(t.Routes))
+	for _, rt := range t.Routes {
+		route := manifestRoute{
+			Method:  rt.API.HTTPMethod,
+			Path:    rt.API.Path,
+			Kind:    rt.API.Kind.String(),
+			Service: string(rt.Service.Desc.FullName()),
+			RPC:     rt.Name(),
+			Source:  rt.Service.Desc.ParentFile().Path(),
+		}
+		if rt.API.Method != nil {
+			route.Input = string(rt.API.Method.Input.Desc.FullName())
+			route.Output = string(rt.API.Method.Output.Desc.FullName())
+		}
+		routes = append(routes, route)
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	raw, err := json.MarshalIndent(map[string]interface{}{
+		"routes": routes,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	g.P(string(raw))
+	return nil
+}
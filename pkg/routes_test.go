@@ -0,0 +1,70 @@
+package pkg
+
+import "testing"
+
+func TestPathsOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "/commands/create", b: "/commands/create", want: true},
+		{a: "/commands/create", b: "/commands/cancel"},
+		{a: "/commands/create", b: "/commands/create/all"},
+		{a: "/commands/:id", b: "/commands/create", want: true},
+		{a: "/commands/:id", b: "/commands/:name", want: true},
+		{a: "/orders/:id/items", b: "/orders/:id/lines"},
+		{a: "/orders/:id/items", b: "/orders/:order/items", want: true},
+		{a: "/orders/all/:id", b: "/orders/:order/:name", want: true},
+		{a: "/files/*path", b: "/files/report/pdf", want: true},
+		{a: "/commands/-/batch", b: "/commands/:name"},
+		{a: "/commands/-/batch", b: "/commands/:id/batch", want: true},
+		// wildcards in the middle of a segment
+		{a: "/commands:batch", b: "/commands"},
+		{a: "/commands:batch", b: "/commandsAll", want: true},
+		{a: "/commands:batch", b: "/queries"},
+		{a: "/files/report:format", b: "/files/report"},
+		{a: "/files/report:format", b: "/files/report.pdf", want: true},
+		{a: "/files/report:format", b: "/files/summary.pdf"},
+		{a: "/files/report:format", b: "/files/report:kind", want: true},
+		{a: "/files/report:format", b: "/files/rep:kind", want: true},
+		{a: "/files/report*rest", b: "/files/report.pdf/raw", want: true},
+		{a: "/files/report*rest", b: "/files/summary/raw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := pathsOverlap(tt.a, tt.b); got != tt.want {
+				t.Errorf("pathsOverlap(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := pathsOverlap(tt.b, tt.a); got != tt.want {
+				t.Errorf("pathsOverlap(%s, %s) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteTable(t *testing.T) {
+	table := RouteTable{}
+	routes := []struct {
+		method, path string
+		conflict     string
+	}{
+		{method: "POST", path: "/commands/placeOrder"},
+		{method: "GET", path: "/commands/placeOrder"},
+		{method: "POST", path: "/commands/-/batch"},
+		{method: "POST", path: "/commands/:id", conflict: "/commands/placeOrder"},
+		{method: "GET", path: "/commands/placeOrder", conflict: "/commands/placeOrder"},
+		{method: "POST", path: "/queries/listOrders"},
+	}
+	for _, rt := range routes {
+		prev := table.Add(nil, APIPath{HTTPMethod: rt.method, Path: rt.path})
+		switch {
+		case rt.conflict == "" && prev != nil:
+			t.Errorf("%s %s conflicts with %s", rt.method, rt.path, prev.API.Path)
+		case rt.conflict != "" && (prev == nil || prev.API.Path != rt.conflict):
+			t.Errorf("%s %s conflicts with %v, want %s", rt.method, rt.path, prev, rt.conflict)
+		}
+	}
+	if len(table.Routes) != 4 {
+		t.Errorf("%d routes registered, want 4", len(table.Routes))
+	}
+}
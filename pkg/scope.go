@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// InputScope is the scope within which an input message may only be used by
+// a single rpc
+type InputScope string
+
+const (
+	ServiceScope InputScope = "service"
+	PackageScope InputScope = "package"
+	RequestScope InputScope = "request"
+	NoScope      InputScope = "none"
+)
+
+// ParseInputScope validates an input scope name
+func ParseInputScope(in string) (InputScope, error) {
+	switch s := InputScope(in); s {
+	case ServiceScope, PackageScope, RequestScope, NoScope:
+		return s, nil
+	}
+	return "", fmt.Errorf(
+		"unknown input scope %q, expected service, package, request or none",
+		in,
+	)
+}
+
+// InputTable tracks the rpcs using each input message across every file of
+// a request
+type InputTable struct {
+	Scope InputScope
+	used  map[string]*protogen.Method
+}
+
+// Use records rpc as a user of its input message, returning the rpc which
+// already uses it within the scope if any
+func (t *InputTable) Use(
+	srv *protogen.Service,
+	rpc *protogen.Method,
+) *protogen.Method {
+	key := string(rpc.Input.Desc.FullName())
+	switch t.Scope {
+	case NoScope:
+		return nil
+	case ServiceScope:
+		key = string(srv.Desc.FullName()) + "/" + key
+	case PackageScope:
+		key = string(srv.Desc.ParentFile().Package()) + "/" + key
+	}
+	if t.used == nil {
+		t.used = map[string]*protogen.Method{}
+	}
+	if prev, ok := t.used[key]; ok {
+		return prev
+	}
+	t.used[key] = rpc
+	return nil
+}
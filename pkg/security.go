@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"techunicorn.com/protoc-gen-gocqrshttp/custom/annotations"
+)
+
+// SecuritySchemes reads the custom.security_schemes option of a file
+func SecuritySchemes(file *protogen.File) []*annotations.SecurityScheme {
+	options, ok := file.Desc.Options().(*descriptorpb.FileOptions)
+	if !ok || !proto.HasExtension(options, annotations.E_SecuritySchemes) {
+		return nil
+	}
+	schemes, _ := proto.GetExtension(
+		options,
+		annotations.E_SecuritySchemes,
+	).([]*annotations.SecurityScheme)
+	return schemes
+}
+
+// generateOpenAPISecurity generates the security requirements of an
+// operation from its custom.authorization option
+func generateOpenAPISecurity(
+	g *protogen.GeneratedFile,
+	api APIPath,
+	schemes []*annotations.SecurityScheme,
+) {
+	auth := api.Authorization
+	if auth == nil {
+		return
+	}
+	if auth.AllowAnonymous {
+		g.P("      security: []")
+		return
+	}
+
+	accepted := map[string]struct{}{}
+	for _, name := range auth.Schemes {
+		accepted[name] = struct{}{}
+	}
+	requirements := []string{}
+	for _, scheme := range schemes {
+		if _, ok := accepted[scheme.Name]; len(accepted) != 0 && !ok {
+			continue
+		}
+		scopes := []string{}
+		if scheme.Type == "oauth2" || scheme.Type == "openIdConnect" {
+			scopes = auth.Scopes
+		}
+		requirements = append(
+			requirements,
+			"        - "+yamlString(scheme.Name)+": "+yamlList(scopes),
+		)
+	}
+	if len(requirements) != 0 {
+		g.P("      security:")
+		for _, req := range requirements {
+			g.P(req)
+		}
+	}
+	if len(auth.Roles) != 0 {
+		g.P("      x-roles: ", yamlList(auth.Roles))
+	}
+	if len(auth.Permissions) != 0 {
+		g.P("      x-permissions: ", yamlList(auth.Permissions))
+	}
+}
+
+// generateOpenAPISecuritySchemes generates the security scheme components
+// declared by the custom.security_schemes option
+func generateOpenAPISecuritySchemes(
+	g *protogen.GeneratedFile,
+	schemes []*annotations.SecurityScheme,
+) {
+	if len(schemes) == 0 {
+		return
+	}
+	g.P("  securitySchemes:")
+	for _, scheme := range schemes {
+		g.P("    ", yamlString(scheme.Name), ":")
+		g.P("      type: ", yamlString(scheme.Type))
+		if scheme.Description != "" {
+			g.P("      description: ", yamlString(scheme.Description))
+		}
+		switch scheme.Type {
+		case "http":
+			g.P("      scheme: ", yamlString(scheme.Scheme))
+			if scheme.BearerFormat != "" {
+				g.P("      bearerFormat: ", yamlString(scheme.BearerFormat))
+			}
+		case "apiKey":
+			g.P("      in: ", yamlString(scheme.In))
+			g.P("      name: ", yamlString(scheme.ParameterName))
+		case "openIdConnect":
+			g.P("      openIdConnectUrl: ", yamlString(scheme.OpenIdConnectUrl))
+		case "oauth2":
+			g.P("      flows:")
+			g.P("        ", yamlString(scheme.Flow), ":")
+			if scheme.AuthorizationUrl != "" {
+				g.P("          authorizationUrl: ", yamlString(scheme.AuthorizationUrl))
+			}
+			if scheme.TokenUrl != "" {
+				g.P("          tokenUrl: ", yamlString(scheme.TokenUrl))
+			}
+			if len(scheme.Scopes) == 0 {
+				g.P("          scopes: {}")
+				continue
+			}
+			g.P("          scopes:")
+			names := make([]string, 0, len(scheme.Scopes))
+			for name := range scheme.Scopes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				g.P("            ", yamlString(name), ": ", yamlString(scheme.Scopes[name]))
+			}
+		}
+	}
+}
+
+// yamlList formats strings as a yaml flow sequence
+func yamlList(in []string) string {
+	out := "["
+	for i, item := range in {
+		if i != 0 {
+			out += ", "
+		}
+		out += yamlString(item)
+	}
+	return out + "]"
+}
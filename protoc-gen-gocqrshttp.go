@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"strings"
 
@@ -8,6 +9,7 @@ import (
 	// "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"techunicorn.com/protoc-gen-gocqrshttp/custom/annotations"
 	"techunicorn.com/protoc-gen-gocqrshttp/pkg"
@@ -18,11 +20,192 @@ import (
 	// "google.golang.org/protobuf/types/descriptorpb"
 )
 
+var (
+	flags  flag.FlagSet
+	strict = flags.Bool(
+		"strict",
+		false,
+		"require the custom.documentation option on every rpc",
+	)
+	commandSuffixes = listFlag(
+		"command_suffix",
+		"Command",
+		"suffix of command input messages, may be repeated",
+	)
+	querySuffixes = listFlag(
+		"query_suffix",
+		"Query",
+		"suffix of query input messages, may be repeated",
+	)
+	eventSuffixes = listFlag(
+		"event_suffix",
+		"Event",
+		"suffix of event messages, may be repeated",
+	)
+	categories = listFlag(
+		"category",
+		"",
+		"suffix:prefix routing other input messages, may be repeated",
+	)
+	commandPrefix = flags.String(
+		"command_prefix",
+		"commands",
+		"route prefix of commands",
+	)
+	queryPrefix = flags.String(
+		"query_prefix",
+		"queries",
+		"route prefix of queries",
+	)
+	uniqueInputs = flags.String(
+		"unique_inputs",
+		string(pkg.ServiceScope),
+		"scope within which an input message may only be used once, service, package, request or none",
+	)
+	routeManifest = flags.String(
+		"route_manifest",
+		"",
+		"name of a json file to write the route table to, with the routes of the services of imported files",
+	)
+	maxBodyBytes = flags.Int64(
+		"max_body_bytes",
+		4<<20,
+		"default maximum size of request bodies in bytes, 0 for no limit",
+	)
+	pathCase = flags.String(
+		"path_case",
+		string(pkg.CamelCase),
+		"casing of derived path segments, camel, kebab or snake",
+	)
+	consumers = flags.Bool(
+		"consumers",
+		false,
+		"generate queue consumers of the commands of every service",
+	)
+)
+
+// stringList is a flag that may be repeated, the first occurrence replacing
+// its default value
+type stringList struct {
+	values []string
+	set    bool
+}
+
+func listFlag(name string, value string, usage string) *stringList {
+	l := &stringList{}
+	if value != "" {
+		l.values = []string{value}
+	}
+	flags.Var(l, name, usage)
+	return l
+}
+
+func (l *stringList) String() string {
+	return strings.Join(l.values, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	if !l.set {
+		l.values = nil
+		l.set = true
+	}
+	l.values = append(l.values, value)
+	return nil
+}
+
+// namingRules builds the naming rules from the plugin options
+func namingRules() (pkg.NamingRules, error) {
+	casing, err := pkg.ParseCasing(*pathCase)
+	if err != nil {
+		return pkg.NamingRules{}, err
+	}
+	rules := pkg.NamingRules{
+		CommandSuffixes: commandSuffixes.values,
+		QuerySuffixes:   querySuffixes.values,
+		EventSuffixes:   eventSuffixes.values,
+		CommandPrefix:   *commandPrefix,
+		QueryPrefix:     *queryPrefix,
+		Casing:          casing,
+	}
+	for _, cat := range categories.values {
+		sfx, prfx, ok := strings.Cut(cat, ":")
+		if !ok || sfx == "" || prfx == "" {
+			return pkg.NamingRules{}, fmt.Errorf(
+				"invalid category %q, expected suffix:prefix",
+				cat,
+			)
+		}
+		rules.Categories = append(rules.Categories, pkg.Category{
+			Suffix: sfx,
+			Prefix: prfx,
+		})
+	}
+	return rules, nil
+}
+
 func main() {
-	protogen.Options{}.Run(func(p *protogen.Plugin) error {
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(func(p *protogen.Plugin) error {
+		rules, err := namingRules()
+		if err != nil {
+			return err
+		}
+
+		scope, err := pkg.ParseInputScope(*uniqueInputs)
+		if err != nil {
+			return err
+		}
+
+		diags := pkg.Diagnostics{}
+		inputs := pkg.InputTable{Scope: scope}
+		routes := pkg.RouteTable{}
+		messages := pkg.IndexMessages(p.Files)
+		srvs := map[*protogen.File][]pkg.Server{}
+		imported := pkg.InputTable{Scope: scope}
 		for _, f := range p.Files {
 			if f.Generate {
-				if err := GenerateFile(p, f); err != nil {
+				srvs[f] = ParseFile(
+					f,
+					rules,
+					messages,
+					&inputs,
+					&routes,
+					&diags,
+				)
+				continue
+			}
+			// the services of imported files are served alongside those
+			// generated here, their routes are tracked to detect conflicts
+			// while their own violations are left to the runs generating them
+			ParseFile(
+				f,
+				rules,
+				messages,
+				&imported,
+				&routes,
+				&pkg.Diagnostics{},
+			)
+		}
+		if err := diags.Err(); err != nil {
+			return err
+		}
+
+		if *routeManifest != "" {
+			err := pkg.GenerateRouteManifest(
+				&routes,
+				p.NewGeneratedFile(*routeManifest, ""),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		redactions := pkg.FindRedactions(p.Files)
+		for _, f := range p.Files {
+			if f.Generate {
+				GenerateRedactFile(p, f, redactions)
+				if err := GenerateFile(p, f, srvs[f]); err != nil {
 					return err
 				}
 			}
@@ -35,6 +218,7 @@ func main() {
 func GenerateFile(
 	plugin *protogen.Plugin,
 	file *protogen.File,
+	srvs []pkg.Server,
 ) error {
 	isGenerated := false
 	for _, srv := range file.Services {
@@ -50,7 +234,6 @@ func GenerateFile(
 		return nil
 	}
 	plugin.SupportedFeatures = 1
-	protojsonPackage := protogen.GoImportPath("google.golang.org/protobuf/encoding/protojson")
 	gofilename := file.GeneratedFilenamePrefix + ".http.go"
 	gohttp := plugin.NewGeneratedFile(gofilename, file.GoImportPath)
 
@@ -58,12 +241,6 @@ func GenerateFile(
 	gohttp.P("// source: ", file.Desc.Path())
 	gohttp.P()
 	gohttp.P("package ", file.GoPackageName)
-	gohttp.P("const InternalContextKey = \"inCxt\"")
-	gohttp.P(
-		"var protomarsh = ",
-		protojsonPackage.Ident("MarshalOptions"),
-		"{EmitUnpopulated: true}",
-	)
 
 	yamlfilename := file.GeneratedFilenamePrefix + ".http.yaml"
 	openapi := plugin.NewGeneratedFile(yamlfilename, file.GoImportPath)
@@ -74,61 +251,837 @@ func GenerateFile(
 	jsonfilename := file.GeneratedFilenamePrefix + ".http.json"
 	openapijson := plugin.NewGeneratedFile(jsonfilename, file.GoImportPath)
 
-	cnqs := map[string]struct{}{}
+	busfilename := file.GeneratedFilenamePrefix + ".bus.go"
+	gobus := plugin.NewGeneratedFile(busfilename, file.GoImportPath)
+
+	gobus.P("// Code generated by protoc-gen-gohttp. DO NOT EDIT.")
+	gobus.P("// source: ", file.Desc.Path())
+	gobus.P()
+	gobus.P("package ", file.GoPackageName)
+	gobus.P()
+	pkg.GenerateBuses(srvs, gobus)
+	pkg.GenerateEventPublishers(srvs, gobus)
+
+	if *consumers {
+		consumerfilename := file.GeneratedFilenamePrefix + ".consumer.go"
+		goconsumer := plugin.NewGeneratedFile(consumerfilename, file.GoImportPath)
+
+		goconsumer.P("// Code generated by protoc-gen-gohttp. DO NOT EDIT.")
+		goconsumer.P("// source: ", file.Desc.Path())
+		goconsumer.P()
+		goconsumer.P("package ", file.GoPackageName)
+		goconsumer.P()
+		pkg.GenerateConsumers(srvs, goconsumer)
+	}
+
+	err := pkg.GenerateHTTPServers(srvs, gohttp, file)
+	if err != nil {
+		return err
+	}
+
+	err = pkg.GenerateOpenAPI(srvs, openapi, openapijson, file)
+	if err != nil || !pkg.EmitsEvents(srvs) {
+		return err
+	}
+
+	asyncfilename := file.GeneratedFilenamePrefix + ".asyncapi.yaml"
+	asyncapi := plugin.NewGeneratedFile(asyncfilename, file.GoImportPath)
+
+	asyncapi.P("# Code generated by protoc-gen-gohttp. DO NOT EDIT.")
+	asyncapi.P("# source: ", file.Desc.Path())
+
+	asyncjsonfilename := file.GeneratedFilenamePrefix + ".asyncapi.json"
+	asyncapijson := plugin.NewGeneratedFile(asyncjsonfilename, file.GoImportPath)
+
+	return pkg.GenerateAsyncAPI(srvs, asyncapi, asyncapijson, file)
+}
+
+// GenerateRedactFile generates the Redact methods of the messages of a file
+// with sensitive fields, whether it has services or not
+func GenerateRedactFile(
+	plugin *protogen.Plugin,
+	file *protogen.File,
+	redactions pkg.Redactions,
+) {
+	if !redactions.HasRedactions(file) {
+		return
+	}
+	redactfilename := file.GeneratedFilenamePrefix + ".redact.go"
+	goredact := plugin.NewGeneratedFile(redactfilename, file.GoImportPath)
+
+	goredact.P("// Code generated by protoc-gen-gohttp. DO NOT EDIT.")
+	goredact.P("// source: ", file.Desc.Path())
+	goredact.P()
+	goredact.P("package ", file.GoPackageName)
+	goredact.P()
+	pkg.GenerateRedactions(redactions, file, goredact)
+}
+
+// ParseFile builds the servers of a file, reporting any convention violation
+// to diags
+func ParseFile(
+	file *protogen.File,
+	rules pkg.NamingRules,
+	messages pkg.MessageIndex,
+	inputs *pkg.InputTable,
+	routes *pkg.RouteTable,
+	diags *pkg.Diagnostics,
+) []pkg.Server {
+	schemes := map[string]struct{}{}
+	for _, scheme := range pkg.SecuritySchemes(file) {
+		schemes[scheme.Name] = struct{}{}
+	}
+
 	srvs := []pkg.Server{}
 	for _, srv := range file.Services {
-		// if err := genService(g, srv); err != nil {
-		// 	return err
-		// }
-
+		reported := len(*diags)
 		pths := []pkg.APIPath{}
 		for _, rpc := range srv.Methods {
-			if _, ok := cnqs[rpc.Input.GoIdent.GoName]; !ok {
-				cnqs[rpc.Input.GoIdent.GoName] = struct{}{}
-			} else {
-				return fmt.Errorf("command/query used multiple times %s", rpc.Input.GoIdent.GoName)
+			input := rpc.Input.GoIdent.GoName
+			if prev := inputs.Use(srv, rpc); prev != nil {
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleInputReuse,
+					fmt.Sprintf(
+						"declare a dedicated input message for %s, or relax the unique_inputs option",
+						rpc.Desc.Name(),
+					),
+					"command/query %s already used by rpc %s",
+					rpc.Input.Desc.FullName(),
+					prev.Desc.FullName(),
+				)
+			}
+
+			// every violation of an rpc is reported before moving on to the
+			// next one, those depending on its kind once it has one
+			valid := true
+			kind, prefix, base, classified := rules.Classify(input)
+			op := operation(rpc)
+			switch op.GetKind() {
+			case annotations.Operation_COMMAND:
+				kind, prefix, classified = pkg.KindCommand, rules.CommandPrefix, true
+			case annotations.Operation_QUERY:
+				kind, prefix, classified = pkg.KindQuery, rules.QueryPrefix, true
+			case annotations.Operation_OTHER:
+				if kind != pkg.KindOther {
+					prefix = ""
+				}
+				kind, classified = pkg.KindOther, true
+			}
+			if !classified {
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleInputKind,
+					fmt.Sprintf(
+						"rename %s to end with %s, or set the kind of the (custom.operation) option",
+						input,
+						strings.Join(append(
+							append([]string{}, rules.CommandSuffixes...),
+							rules.QuerySuffixes...,
+						), " or "),
+					),
+					"rpc %s takes %s which is neither a command nor a query",
+					rpc.Desc.FullName(),
+					input,
+				)
+				valid = false
+			}
+			if classified && op.GetCategory() != "" {
+				if kind != pkg.KindOther {
+					diags.Report(
+						file,
+						rpc.Desc,
+						pkg.RuleOperation,
+						"remove the category or set the kind to OTHER",
+						"rpc %s is a %s, categories only apply to operations of the OTHER kind",
+						rpc.Desc.FullName(),
+						kind,
+					)
+					valid = false
+				}
+				prefix = op.GetCategory()
+			}
+			if classified && prefix == "" {
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleOperation,
+					"set the category of the (custom.operation) option",
+					"rpc %s of the OTHER kind has no category",
+					rpc.Desc.FullName(),
+				)
+				valid = false
+			}
+
+			if classified && op.GetAsync() && kind != pkg.KindCommand {
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleCommandOption,
+					"remove async from the (custom.operation) option",
+					"rpc %s is a %s, only commands can be asynchronous",
+					rpc.Desc.FullName(),
+					kind,
+				)
+				valid = false
+			}
+			if classified && op.GetIdempotent() && kind != pkg.KindCommand {
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleCommandOption,
+					"remove idempotent from the (custom.operation) option",
+					"rpc %s is a %s, only commands can be idempotent",
+					rpc.Desc.FullName(),
+					kind,
+				)
+				valid = false
+			}
+
+			emits := []*protogen.Message{}
+			if classified {
+				var ok bool
+				emits, ok = events(file, rpc, kind, op, rules, messages, diags)
+				valid = valid && ok
+			}
+
+			if classified && httpOption(rpc).GetCacheControl() != "" && kind != pkg.KindQuery {
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleQueryOption,
+					"remove cache_control from the (custom.http) option",
+					"rpc %s is a %s, only queries can be cached",
+					rpc.Desc.FullName(),
+					kind,
+				)
+				valid = false
 			}
+			etagField := ""
+			if kind == pkg.KindQuery {
+				field, ok := nominatedField(
+					file,
+					rpc.Output,
+					pkg.RuleQueryOption,
+					"etag",
+					(*annotations.Field).GetEtag,
+					diags,
+				)
+				valid = valid && ok
+				etagField = field
+			}
+			versionField, ok := nominatedField(
+				file,
+				rpc.Input,
+				pkg.RuleCommandOption,
+				"expected version",
+				(*annotations.Field).GetExpectedVersion,
+				diags,
+			)
+			valid = valid && ok
+			if classified && versionField != "" && kind != pkg.KindCommand {
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleCommandOption,
+					fmt.Sprintf(
+						"remove expected_version from the (custom.field) option of %s",
+						versionField,
+					),
+					"rpc %s is a %s, only commands can expect a version",
+					rpc.Desc.FullName(),
+					kind,
+				)
+				valid = false
+			}
+			bindings, ok := fieldBindings(file, rpc.Input, versionField, diags)
+			valid = valid && ok
+			// queries are served with GET for conditional requests to be
+			// answered with 304, their input read from the query string
+			httpMethod := "POST"
+			if kind == pkg.KindQuery {
+				httpMethod = "GET"
+				queries, ok := queryBindings(file, rpc.Input, diags)
+				valid = valid && ok
+				bindings = append(bindings, queries...)
+			}
+			status := int(httpOption(rpc).GetStatus())
+			switch {
+			case status != 0 && (status < 200 || status > 299):
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleResponse,
+					"set a 2xx status in the (custom.http) option",
+					"rpc %s answers with status %d, which is not a success",
+					rpc.Desc.FullName(),
+					status,
+				)
+				valid = false
+			case status != 0 && op.GetAsync():
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleResponse,
+					"remove the status from the (custom.http) option",
+					"rpc %s is asynchronous and answers with 202, not %d",
+					rpc.Desc.FullName(),
+					status,
+				)
+				valid = false
+			}
+			responseHeaders, ok := fieldResponseHeaders(file, rpc.Output, diags)
+			valid = valid && ok
+
+			segment := rules.Casing.Format(base)
+			if op.GetRoute() != "" {
+				segment = op.GetRoute()
+			}
+			path := rules.Route(prefix, segment)
 
-			var path string
-			if strings.Contains(rpc.Input.GoIdent.GoName, "Command") {
-				cmd := pkg.ToPrivateName(strings.TrimSuffix(rpc.Input.GoIdent.GoName, "Command"))
-				path = "/commands/" + cmd
-			} else if strings.Contains(rpc.Input.GoIdent.GoName, "Query") {
-				cmd := pkg.ToPrivateName(strings.TrimSuffix(rpc.Input.GoIdent.GoName, "Query"))
-				path = "/queries/" + cmd
-			} else {
-				return fmt.Errorf("non command/query model used as input %s", rpc.Input.GoIdent.GoName)
+			doc := documentation(rpc)
+			if doc == nil {
+				if *strict {
+					diags.Report(
+						file,
+						rpc.Desc,
+						pkg.RuleDocumentation,
+						"add option (custom.documentation) = { summary: \"...\" }",
+						"documentation missing from rpc %s.%s",
+						srv.Desc.FullName(),
+						rpc.Desc.Name(),
+					)
+					valid = false
+				}
+				doc = &annotations.Documentation{}
+				doc.Summary, doc.Description = pkg.CommentDocumentation(
+					rpc.Comments.Leading,
+				)
 			}
 
-			options, ok := rpc.Desc.Options().(*descriptorpb.MethodOptions)
-			if !ok {
-				return fmt.Errorf("documentation missing from rpc")
+			auth := authorization(rpc)
+			for _, name := range auth.GetSchemes() {
+				if _, ok := schemes[name]; !ok {
+					diags.Report(
+						file,
+						rpc.Desc,
+						pkg.RuleSecurityScheme,
+						"declare the scheme with the (custom.security_schemes) file option",
+						"rpc %s accepts undeclared security scheme %s",
+						rpc.Desc.FullName(),
+						name,
+					)
+					valid = false
+				}
 			}
 
-			doc, ok := proto.GetExtension(options, annotations.E_Documentation).(*annotations.Documentation)
-			if !ok {
-				return fmt.Errorf("documentation missing from rpc")
+			bodyLimit := *maxBodyBytes
+			if limit := httpOption(rpc).GetMaxBodyBytes(); limit != 0 {
+				bodyLimit = limit
 			}
 
-			pths = append(pths, pkg.APIPath{
-				Method:      rpc,
-				Path:        path,
-				Summary:     doc.Summary,
-				Description: doc.Description,
-				Tags:        doc.Tags,
-				HTTPMethod:  "POST",
-			})
+			api := pkg.APIPath{
+				Method:          rpc,
+				Kind:            kind,
+				Path:            path,
+				Summary:         doc.Summary,
+				Description:     doc.Description,
+				Tags:            doc.Tags,
+				HTTPMethod:      httpMethod,
+				Authorization:   auth,
+				MaxBodyBytes:    bodyLimit,
+				Idempotent:      op.GetIdempotent(),
+				Async:           op.GetAsync(),
+				Emits:           emits,
+				ETag:            kind == pkg.KindQuery,
+				ETagField:       etagField,
+				CacheControl:    httpOption(rpc).GetCacheControl(),
+				VersionField:    versionField,
+				Bindings:        bindings,
+				Status:          status,
+				ResponseHeaders: responseHeaders,
+			}
+			if !valid {
+				continue
+			}
+			if prev := routes.Add(srv, api); prev != nil {
+				diags.Report(
+					file,
+					rpc.Desc,
+					pkg.RuleRouteConflict,
+					"set a distinct route in the (custom.operation) option",
+					"route %s %s of rpc %s conflicts with route %s %s of %s",
+					api.HTTPMethod,
+					api.Path,
+					rpc.Desc.FullName(),
+					prev.API.HTTPMethod,
+					prev.API.Path,
+					prev.Name(),
+				)
+				continue
+			}
+			pths = append(pths, api)
+		}
+		operations := ""
+		for _, api := range pths {
+			if api.Async {
+				operations = operationsRoute(file, srv, rules, routes, diags)
+				break
+			}
+		}
+		var batch *pkg.APIPath
+		// rpcs already reported are left out of the checks of the service
+		if serviceOption(srv).GetBatch() && len(*diags) == reported {
+			batch = batchRoute(file, srv, pths, rules, routes, diags)
 		}
 		srvs = append(srvs, pkg.Server{
-			Service: srv,
-			Paths:   pths,
+			Service:        srv,
+			Paths:          pths,
+			OperationsPath: operations,
+			Batch:          batch,
 		})
 	}
+	return srvs
+}
 
-	err := pkg.GenerateHTTPServers(srvs, gohttp, file)
-	if err != nil {
-		return err
+// events resolves the event messages an rpc emits, reporting names that are
+// not those of events
+func events(
+	file *protogen.File,
+	rpc *protogen.Method,
+	kind pkg.Kind,
+	op *annotations.Operation,
+	rules pkg.NamingRules,
+	messages pkg.MessageIndex,
+	diags *pkg.Diagnostics,
+) ([]*protogen.Message, bool) {
+	if len(op.GetEmits()) != 0 && kind != pkg.KindCommand {
+		diags.Report(
+			file,
+			rpc.Desc,
+			pkg.RuleEvent,
+			"remove emits from the (custom.operation) option",
+			"rpc %s is a %s, only commands emit events",
+			rpc.Desc.FullName(),
+			kind,
+		)
+		return nil, false
 	}
 
-	return pkg.GenerateOpenAPI(srvs, openapi, openapijson, file)
+	emits, ok := []*protogen.Message{}, true
+	for _, name := range op.GetEmits() {
+		msg := messages.Resolve(file.Desc.Package(), name)
+		switch {
+		case msg == nil:
+			diags.Report(
+				file,
+				rpc.Desc,
+				pkg.RuleEvent,
+				"declare the event message, or import the file declaring it",
+				"rpc %s emits unknown message %s",
+				rpc.Desc.FullName(),
+				name,
+			)
+			ok = false
+		case !rules.IsEvent(msg.GoIdent.GoName):
+			diags.Report(
+				file,
+				rpc.Desc,
+				pkg.RuleEvent,
+				fmt.Sprintf(
+					"rename %s to end with %s",
+					msg.GoIdent.GoName,
+					strings.Join(rules.EventSuffixes, " or "),
+				),
+				"rpc %s emits %s which is not an event",
+				rpc.Desc.FullName(),
+				msg.Desc.FullName(),
+			)
+			ok = false
+		default:
+			emits = append(emits, msg)
+		}
+	}
+	return emits, ok
+}
+
+// batchRoute registers the batch route of a service, returning nil when it
+// has no command that can be batched or the route conflicts
+func batchRoute(
+	file *protogen.File,
+	srv *protogen.Service,
+	pths []pkg.APIPath,
+	rules pkg.NamingRules,
+	routes *pkg.RouteTable,
+	diags *pkg.Diagnostics,
+) *pkg.APIPath {
+	batchable := false
+	for _, api := range pths {
+		batchable = batchable || api.Batchable()
+	}
+	if !batchable {
+		diags.Report(
+			file,
+			srv.Desc,
+			pkg.RuleCommandOption,
+			"remove batch from the (custom.service) option",
+			"service %s has no command that can be batched, async and idempotent commands cannot be",
+			srv.Desc.FullName(),
+		)
+		return nil
+	}
+
+	api := pkg.APIPath{
+		Kind:         pkg.KindCommand,
+		Path:         rules.BatchRoute(),
+		HTTPMethod:   "POST",
+		MaxBodyBytes: *maxBodyBytes,
+	}
+	if prev := routes.AddBuiltin(srv, pkg.BatchRoute, api); prev != nil {
+		diags.Report(
+			file,
+			srv.Desc,
+			pkg.RuleRouteConflict,
+			"enable batches on a single service, or change the command_prefix option",
+			"route %s %s of service %s conflicts with route %s %s of %s",
+			api.HTTPMethod,
+			api.Path,
+			srv.Desc.FullName(),
+			prev.API.HTTPMethod,
+			prev.API.Path,
+			prev.Name(),
+		)
+		return nil
+	}
+	return &api
+}
+
+// operationsRoute registers the route polling the operations of the async
+// commands of a service, returning the path the operations are located under,
+// empty when the route conflicts
+func operationsRoute(
+	file *protogen.File,
+	srv *protogen.Service,
+	rules pkg.NamingRules,
+	routes *pkg.RouteTable,
+	diags *pkg.Diagnostics,
+) string {
+	base := rules.Route("operations", rules.Casing.Format(srv.GoName))
+	api := pkg.APIPath{
+		Kind:       pkg.KindQuery,
+		Path:       base + "/:id",
+		HTTPMethod: "GET",
+	}
+	if prev := routes.AddBuiltin(srv, pkg.OperationsRoute, api); prev != nil {
+		diags.Report(
+			file,
+			srv.Desc,
+			pkg.RuleRouteConflict,
+			"rename the service, or register it on another group",
+			"route %s %s of service %s conflicts with route %s %s of %s",
+			api.HTTPMethod,
+			api.Path,
+			srv.Desc.FullName(),
+			prev.API.HTTPMethod,
+			prev.API.Path,
+			prev.Name(),
+		)
+		return ""
+	}
+	return base
+}
+
+// nominatedField finds the field of a message nominated by a custom.field
+// option, reporting fields that cannot hold a version
+func nominatedField(
+	file *protogen.File,
+	msg *protogen.Message,
+	rule string,
+	option string,
+	nominated func(*annotations.Field) bool,
+	diags *pkg.Diagnostics,
+) (string, bool) {
+	name, ok := "", true
+	for _, field := range msg.Fields {
+		options, _ := field.Desc.Options().(*descriptorpb.FieldOptions)
+		if options == nil || !proto.HasExtension(options, annotations.E_Field) {
+			continue
+		}
+		opt, _ := proto.GetExtension(options, annotations.E_Field).(*annotations.Field)
+		if !nominated(opt) {
+			continue
+		}
+		switch {
+		case !versionKind(field.Desc):
+			diags.Report(
+				file,
+				field.Desc,
+				rule,
+				fmt.Sprintf("nominate a string or integer field as the %s", option),
+				"field %s cannot be an %s, only string and integer fields can",
+				field.Desc.FullName(),
+				option,
+			)
+			ok = false
+		case name != "":
+			diags.Report(
+				file,
+				field.Desc,
+				rule,
+				fmt.Sprintf("nominate a single field as the %s", option),
+				"field %s is an %s, as is field %s",
+				field.Desc.FullName(),
+				option,
+				name,
+			)
+			ok = false
+		default:
+			name = string(field.Desc.Name())
+		}
+	}
+	return name, ok
+}
+
+// fieldBindings lists the fields of an rpc input bound to a request header
+// or cookie by a custom.field option, reporting invalid bindings
+func fieldBindings(
+	file *protogen.File,
+	msg *protogen.Message,
+	versionField string,
+	diags *pkg.Diagnostics,
+) ([]pkg.Binding, bool) {
+	bindings, ok := []pkg.Binding{}, true
+	for _, field := range msg.Fields {
+		options, _ := field.Desc.Options().(*descriptorpb.FieldOptions)
+		if options == nil || !proto.HasExtension(options, annotations.E_Field) {
+			continue
+		}
+		opt, _ := proto.GetExtension(options, annotations.E_Field).(*annotations.Field)
+		binding := pkg.Binding{Field: field, Required: opt.GetRequired()}
+		switch {
+		case opt.GetHeader() != "" && opt.GetCookie() != "":
+			diags.Report(
+				file,
+				field.Desc,
+				pkg.RuleBinding,
+				"remove either header or cookie from the (custom.field) option",
+				"field %s is bound to both header %s and cookie %s",
+				field.Desc.FullName(),
+				opt.GetHeader(),
+				opt.GetCookie(),
+			)
+			ok = false
+			continue
+		case opt.GetHeader() != "":
+			binding.In, binding.Name = pkg.InHeader, opt.GetHeader()
+		case opt.GetCookie() != "":
+			binding.In, binding.Name = pkg.InCookie, opt.GetCookie()
+		case opt.GetRequired():
+			diags.Report(
+				file,
+				field.Desc,
+				pkg.RuleBinding,
+				"set the header or cookie of the (custom.field) option",
+				"field %s is required but bound to neither a header nor a cookie",
+				field.Desc.FullName(),
+			)
+			ok = false
+			continue
+		default:
+			continue
+		}
+		switch {
+		case field.Desc.IsList() || field.Desc.IsMap() ||
+			field.Desc.Kind() == protoreflect.MessageKind ||
+			field.Desc.Kind() == protoreflect.GroupKind:
+			diags.Report(
+				file,
+				field.Desc,
+				pkg.RuleBinding,
+				fmt.Sprintf("bind a singular scalar field to the %s", binding.In),
+				"field %s cannot be bound to %s %s, only singular scalar fields can",
+				field.Desc.FullName(),
+				binding.In,
+				binding.Name,
+			)
+			ok = false
+		case string(field.Desc.Name()) == versionField:
+			diags.Report(
+				file,
+				field.Desc,
+				pkg.RuleBinding,
+				fmt.Sprintf("remove the %s from the (custom.field) option", binding.In),
+				"field %s is the expected version, populated from If-Match, and cannot be bound to %s %s",
+				field.Desc.FullName(),
+				binding.In,
+				binding.Name,
+			)
+			ok = false
+		default:
+			bindings = append(bindings, binding)
+		}
+	}
+	return bindings, ok
+}
+
+// queryBindings binds the fields of a query input that are not bound to a
+// header or cookie to the query parameters of their json name, reporting
+// fields that cannot be read from a query string
+func queryBindings(
+	file *protogen.File,
+	msg *protogen.Message,
+	diags *pkg.Diagnostics,
+) ([]pkg.Binding, bool) {
+	bindings, ok := []pkg.Binding{}, true
+	for _, field := range msg.Fields {
+		options, _ := field.Desc.Options().(*descriptorpb.FieldOptions)
+		if options != nil && proto.HasExtension(options, annotations.E_Field) {
+			opt, _ := proto.GetExtension(options, annotations.E_Field).(*annotations.Field)
+			if opt.GetHeader() != "" || opt.GetCookie() != "" {
+				continue
+			}
+		}
+		if field.Desc.IsMap() ||
+			field.Desc.Kind() == protoreflect.MessageKind ||
+			field.Desc.Kind() == protoreflect.GroupKind {
+			diags.Report(
+				file,
+				field.Desc,
+				pkg.RuleBinding,
+				"make the field a scalar or a list of scalars",
+				"field %s of query input %s cannot be read from the query string, only scalar fields and lists of scalars can",
+				field.Desc.FullName(),
+				msg.Desc.FullName(),
+			)
+			ok = false
+			continue
+		}
+		bindings = append(bindings, pkg.Binding{
+			Field: field,
+			In:    pkg.InQuery,
+			Name:  field.Desc.JSONName(),
+		})
+	}
+	return bindings, ok
+}
+
+// fieldResponseHeaders lists the fields of an rpc output mapped to a
+// response header by a custom.field option, reporting invalid mappings
+func fieldResponseHeaders(
+	file *protogen.File,
+	msg *protogen.Message,
+	diags *pkg.Diagnostics,
+) ([]pkg.ResponseHeader, bool) {
+	headers, ok := []pkg.ResponseHeader{}, true
+	for _, field := range msg.Fields {
+		options, _ := field.Desc.Options().(*descriptorpb.FieldOptions)
+		if options == nil || !proto.HasExtension(options, annotations.E_Field) {
+			continue
+		}
+		opt, _ := proto.GetExtension(options, annotations.E_Field).(*annotations.Field)
+		if opt.GetResponseHeader() == "" {
+			continue
+		}
+		if field.Desc.IsList() || field.Desc.IsMap() ||
+			field.Desc.Kind() == protoreflect.MessageKind ||
+			field.Desc.Kind() == protoreflect.GroupKind {
+			diags.Report(
+				file,
+				field.Desc,
+				pkg.RuleResponse,
+				"map a singular scalar field to the response header",
+				"field %s cannot be mapped to response header %s, only singular scalar fields can",
+				field.Desc.FullName(),
+				opt.GetResponseHeader(),
+			)
+			ok = false
+			continue
+		}
+		headers = append(headers, pkg.ResponseHeader{
+			Field: field,
+			Name:  opt.GetResponseHeader(),
+		})
+	}
+	return headers, ok
+}
+
+// versionKind reports whether a field can hold a version
+func versionKind(field protoreflect.FieldDescriptor) bool {
+	if field.IsList() || field.IsMap() {
+		return false
+	}
+	switch field.Kind() {
+	case protoreflect.StringKind,
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return true
+	}
+	return false
+}
+
+// methodOption reads an option of an rpc, returning nil when it is not set
+func methodOption(
+	rpc *protogen.Method,
+	xt protoreflect.ExtensionType,
+) interface{} {
+	options, ok := rpc.Desc.Options().(*descriptorpb.MethodOptions)
+	if !ok || !proto.HasExtension(options, xt) {
+		return nil
+	}
+	return proto.GetExtension(options, xt)
+}
+
+// serviceOption reads the custom.service option of a service, returning nil
+// when it is not set
+func serviceOption(srv *protogen.Service) *annotations.Service {
+	options, ok := srv.Desc.Options().(*descriptorpb.ServiceOptions)
+	if !ok || !proto.HasExtension(options, annotations.E_Service) {
+		return nil
+	}
+	opt, _ := proto.GetExtension(options, annotations.E_Service).(*annotations.Service)
+	return opt
+}
+
+// documentation reads the custom.documentation option of an rpc, returning
+// nil when it is not set
+func documentation(rpc *protogen.Method) *annotations.Documentation {
+	doc, _ := methodOption(
+		rpc,
+		annotations.E_Documentation,
+	).(*annotations.Documentation)
+	return doc
+}
+
+// operation reads the custom.operation option of an rpc, returning nil when
+// it is not set
+func operation(rpc *protogen.Method) *annotations.Operation {
+	op, _ := methodOption(
+		rpc,
+		annotations.E_Operation,
+	).(*annotations.Operation)
+	return op
+}
+
+// authorization reads the custom.authorization option of an rpc, returning
+// nil when it is not set
+func authorization(rpc *protogen.Method) *annotations.Authorization {
+	auth, _ := methodOption(
+		rpc,
+		annotations.E_Authorization,
+	).(*annotations.Authorization)
+	return auth
+}
+
+// httpOption reads the custom.http option of an rpc, returning nil when it
+// is not set
+func httpOption(rpc *protogen.Method) *annotations.Http {
+	opt, _ := methodOption(
+		rpc,
+		annotations.E_Http,
+	).(*annotations.Http)
+	return opt
 }
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+	"techunicorn.com/protoc-gen-gocqrshttp/custom/annotations"
+	"techunicorn.com/protoc-gen-gocqrshttp/pkg"
+)
+
+// diagnostic is a diagnostic expected at the descriptor of a full name
+type diagnostic struct {
+	Rule string
+	At   string
+}
+
+func TestParseFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		routes []string
+		diags  []diagnostic
+	}{
+		{
+			name: "commands and queries",
+			file: `
+				message_type { name: "PlaceOrderCommand" field { name: "id" number: 1 type: TYPE_STRING json_name: "id" } }
+				message_type { name: "ListOrdersQuery" field { name: "page" number: 1 type: TYPE_INT32 json_name: "page" } }
+				message_type { name: "Order" }
+				service {
+					name: "Orders"
+					method { name: "PlaceOrder" input_type: ".test.PlaceOrderCommand" output_type: ".test.Order" }
+					method { name: "ListOrders" input_type: ".test.ListOrdersQuery" output_type: ".test.Order" }
+				}`,
+			routes: []string{"POST /commands/placeOrder", "GET /queries/listOrders"},
+		},
+		{
+			name: "input neither a command nor a query",
+			file: `
+				message_type { name: "CommandeerRequest" }
+				message_type { name: "Order" }
+				service {
+					name: "Orders"
+					method { name: "Commandeer" input_type: ".test.CommandeerRequest" output_type: ".test.Order" }
+				}`,
+			diags: []diagnostic{{Rule: pkg.RuleInputKind, At: "test.Orders.Commandeer"}},
+		},
+		{
+			name: "every violation of an rpc",
+			file: `
+				message_type { name: "FindOrderQuery" }
+				message_type { name: "Order" }
+				service {
+					name: "Orders"
+					method {
+						name: "FindOrder"
+						input_type: ".test.FindOrderQuery"
+						output_type: ".test.Order"
+						options {
+							[custom.operation] { async: true idempotent: true }
+							[custom.http] { status: 99 }
+						}
+					}
+				}`,
+			diags: []diagnostic{
+				{Rule: pkg.RuleCommandOption, At: "test.Orders.FindOrder"},
+				{Rule: pkg.RuleCommandOption, At: "test.Orders.FindOrder"},
+				{Rule: pkg.RuleResponse, At: "test.Orders.FindOrder"},
+			},
+		},
+		{
+			name: "violation shared by two rpcs",
+			file: `
+				message_type {
+					name: "PlaceOrderCommand"
+					field {
+						name: "tenant"
+						number: 1
+						type: TYPE_STRING
+						json_name: "tenant"
+						options { [custom.field] { header: "X-Tenant" cookie: "tenant" } }
+					}
+				}
+				message_type { name: "Order" }
+				service {
+					name: "Orders"
+					method { name: "PlaceOrder" input_type: ".test.PlaceOrderCommand" output_type: ".test.Order" }
+				}
+				service {
+					name: "Shop"
+					method { name: "PlaceOrder" input_type: ".test.PlaceOrderCommand" output_type: ".test.Order" }
+				}`,
+			diags: []diagnostic{{Rule: pkg.RuleBinding, At: "test.PlaceOrderCommand.tenant"}},
+		},
+		{
+			name: "input reused within a service",
+			file: `
+				message_type { name: "PlaceOrderCommand" }
+				message_type { name: "Order" }
+				service {
+					name: "Orders"
+					method { name: "PlaceOrder" input_type: ".test.PlaceOrderCommand" output_type: ".test.Order" }
+					method {
+						name: "Reorder"
+						input_type: ".test.PlaceOrderCommand"
+						output_type: ".test.Order"
+						options { [custom.operation] { route: "reorder" } }
+					}
+				}`,
+			diags: []diagnostic{{Rule: pkg.RuleInputReuse, At: "test.Orders.Reorder"}},
+		},
+		{
+			name: "route conflict",
+			file: `
+				message_type { name: "PlaceOrderCommand" }
+				message_type { name: "CreateOrderCommand" }
+				message_type { name: "Order" }
+				service {
+					name: "Orders"
+					method {
+						name: "PlaceOrder"
+						input_type: ".test.PlaceOrderCommand"
+						output_type: ".test.Order"
+						options { [custom.operation] { route: "orders" } }
+					}
+					method {
+						name: "CreateOrder"
+						input_type: ".test.CreateOrderCommand"
+						output_type: ".test.Order"
+						options { [custom.operation] { route: "orders" } }
+					}
+				}`,
+			diags: []diagnostic{{Rule: pkg.RuleRouteConflict, At: "test.Orders.CreateOrder"}},
+		},
+		{
+			name: "unknown event",
+			file: `
+				message_type { name: "PlaceOrderCommand" }
+				message_type { name: "Order" }
+				service {
+					name: "Orders"
+					method {
+						name: "PlaceOrder"
+						input_type: ".test.PlaceOrderCommand"
+						output_type: ".test.Order"
+						options { [custom.operation] { emits: "OrderPlacedEvent" } }
+					}
+				}`,
+			diags: []diagnostic{{Rule: pkg.RuleEvent, At: "test.Orders.PlaceOrder"}},
+		},
+		{
+			name: "query input with a message field",
+			file: `
+				message_type {
+					name: "ListOrdersQuery"
+					field { name: "filter" number: 1 type: TYPE_MESSAGE type_name: ".test.Order" json_name: "filter" }
+				}
+				message_type { name: "Order" }
+				service {
+					name: "Orders"
+					method { name: "ListOrders" input_type: ".test.ListOrdersQuery" output_type: ".test.Order" }
+				}`,
+			diags: []diagnostic{{Rule: pkg.RuleBinding, At: "test.ListOrdersQuery.filter"}},
+		},
+	}
+	rules := pkg.NamingRules{
+		CommandSuffixes: []string{"Command"},
+		QuerySuffixes:   []string{"Query"},
+		EventSuffixes:   []string{"Event"},
+		CommandPrefix:   "commands",
+		QueryPrefix:     "queries",
+		Casing:          pkg.CamelCase,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, positions := testFile(t, tt.file)
+			diags := pkg.Diagnostics{}
+			srvs := ParseFile(
+				file,
+				rules,
+				pkg.IndexMessages([]*protogen.File{file}),
+				&pkg.InputTable{Scope: pkg.ServiceScope},
+				&pkg.RouteTable{},
+				&diags,
+			)
+
+			got := []diagnostic{}
+			for _, diag := range diags {
+				got = append(got, diagnostic{Rule: diag.Rule, At: positions[diag.Position]})
+			}
+			want := tt.diags
+			if want == nil {
+				want = []diagnostic{}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("diagnostics = %v, want %v\n%v", got, want, diags.Err())
+			}
+
+			routes := []string{}
+			for _, srv := range srvs {
+				for _, api := range srv.Paths {
+					routes = append(routes, api.HTTPMethod+" "+api.Path)
+				}
+			}
+			if tt.routes != nil && !reflect.DeepEqual(routes, tt.routes) {
+				t.Errorf("routes = %v, want %v", routes, tt.routes)
+			}
+		})
+	}
+}
+
+// testFile builds the test.proto file of a request from the text of its
+// descriptor, returning it with the full names of its descriptors keyed by
+// their position. Each message, field, service and method is given a line
+// of its own, as protoc would for a file declaring one per line.
+func testFile(t *testing.T, text string) (*protogen.File, map[pkg.Position]string) {
+	t.Helper()
+	fd := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(text), fd); err != nil {
+		t.Fatal(err)
+	}
+	fd.Name = proto.String("test.proto")
+	fd.Package = proto.String("test")
+	fd.Syntax = proto.String("proto3")
+	fd.Dependency = []string{annotations.File_annotations_proto.Path()}
+	fd.Options = &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")}
+
+	positions := map[pkg.Position]string{}
+	info := &descriptorpb.SourceCodeInfo{}
+	line := 0
+	locate := func(name string, column int32, path ...int32) {
+		line++
+		info.Location = append(info.Location, &descriptorpb.SourceCodeInfo_Location{
+			Path: path,
+			Span: []int32{int32(line), column, column + 1},
+		})
+		positions[pkg.Position{File: "test.proto", Line: line + 1, Column: int(column) + 1}] = name
+	}
+	for mi, msg := range fd.MessageType {
+		locate("test."+msg.GetName(), 0, 4, int32(mi))
+		for fi, field := range msg.Field {
+			locate("test."+msg.GetName()+"."+field.GetName(), 2, 4, int32(mi), 2, int32(fi))
+		}
+	}
+	for si, srv := range fd.Service {
+		locate("test."+srv.GetName(), 0, 6, int32(si))
+		for mi, method := range srv.Method {
+			locate("test."+srv.GetName()+"."+method.GetName(), 2, 6, int32(si), 2, int32(mi))
+		}
+	}
+	fd.SourceCodeInfo = info
+
+	req := &pluginpb.CodeGeneratorRequest{FileToGenerate: []string{fd.GetName()}}
+	seen := map[string]bool{}
+	var add func(protoreflect.FileDescriptor)
+	add = func(dep protoreflect.FileDescriptor) {
+		if seen[dep.Path()] {
+			return
+		}
+		seen[dep.Path()] = true
+		for i := 0; i < dep.Imports().Len(); i++ {
+			add(dep.Imports().Get(i).FileDescriptor)
+		}
+		req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(dep))
+	}
+	add(annotations.File_annotations_proto)
+	req.ProtoFile = append(req.ProtoFile, fd)
+
+	plugin, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, ok := plugin.FilesByPath[fd.GetName()]
+	if !ok {
+		t.Fatalf("%s missing from the request", fd.GetName())
+	}
+	return file, positions
+}
@@ -0,0 +1,70 @@
+// Package runtime is the support library of the code generated by
+// protoc-gen-gocqrshttp
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrUnauthenticated is returned by authorizers when the caller could not
+	// be identified
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrPermissionDenied is returned by authorizers when the caller does not
+	// meet the requirements of an rpc
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrNoAuthorizer is returned when an rpc requires authorization but no
+	// authorizer was registered
+	ErrNoAuthorizer = errors.New("no authorizer registered")
+)
+
+// Requirements are what an rpc requires of its caller, as declared by the
+// custom.authorization option
+type Requirements struct {
+	Scopes      []string
+	Roles       []string
+	Permissions []string
+}
+
+// Authorizer decides whether the caller of an rpc meets its requirements,
+// returning ErrUnauthenticated or ErrPermissionDenied, possibly wrapped, when
+// it does not
+type Authorizer interface {
+	Authorize(ctx context.Context, method string, req Requirements) error
+}
+
+// AuthorizerFunc adapts a function to an Authorizer
+type AuthorizerFunc func(
+	ctx context.Context,
+	method string,
+	req Requirements,
+) error
+
+func (f AuthorizerFunc) Authorize(
+	ctx context.Context,
+	method string,
+	req Requirements,
+) error {
+	return f(ctx, method, req)
+}
+
+// Authorize checks the requirements of an rpc with the authorizer, failing
+// closed when there is no authorizer. Errors of the authorizer without an
+// http status are rendered as 403.
+func Authorize(
+	ctx context.Context,
+	a Authorizer,
+	method string,
+	req Requirements,
+) error {
+	if a == nil {
+		return ErrNoAuthorizer
+	}
+	err := a.Authorize(ctx, method, req)
+	if err != nil && StatusOf(err) == 0 {
+		return NewError(http.StatusForbidden, err)
+	}
+	return err
+}
@@ -0,0 +1,197 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultMaxBatchCommands is the number of commands a batch is limited to
+// when BatchOptions do not set one
+const DefaultMaxBatchCommands = 100
+
+var (
+	// ErrBatchSkipped is returned for the entries of a batch that were not
+	// run because a previous one failed
+	ErrBatchSkipped = NewError(
+		http.StatusFailedDependency,
+		errors.New("skipped after a previous command failed"),
+	)
+	// ErrBatchMiddleware is returned for the entries of a batch whose rpc
+	// has method or tag middlewares, which only run on the route of the rpc
+	ErrBatchMiddleware = NewError(
+		http.StatusBadRequest,
+		errors.New("command has route middlewares, send it to its own route"),
+	)
+)
+
+// BatchOptions configures how the commands of a batch are run
+type BatchOptions struct {
+	// Parallel runs the commands concurrently instead of in order
+	Parallel bool
+	// StopOnError skips the commands following the first one that failed,
+	// cancelling the context of those in flight when running in parallel
+	StopOnError bool
+	// MaxCommands limits the number of commands of a batch, defaulting to
+	// DefaultMaxBatchCommands
+	MaxCommands int
+}
+
+// BatchHandler handles a command of a batch, decoding its payload with
+// decode
+type BatchHandler func(
+	c context.Context,
+	decode func(proto.Message) error,
+) (proto.Message, error)
+
+// batchEntry is a command of a batch request
+type batchEntry struct {
+	Command string          `json:"command"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// batchResult is the outcome of a command of a batch
+type batchResult struct {
+	Status int             `json:"status"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	// err is an error of unknown status, left to the error handling
+	// middlewares instead of being rendered
+	err error
+}
+
+// ServeBatch dispatches the commands of a batch request to the handlers
+// keyed by their name, answering with the result or error of each in order.
+// Batch requests and responses are always json.
+func ServeBatch(
+	ctx *gin.Context,
+	c context.Context,
+	opts BatchOptions,
+	decode DecodeOptions,
+	handlers map[string]BatchHandler,
+) {
+	raw, err := ReadBody(ctx, decode)
+	if err != nil {
+		Error(ctx, err)
+		return
+	}
+	entries := []batchEntry{}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		Error(ctx, NewError(http.StatusBadRequest, err))
+		return
+	}
+	limit := opts.MaxCommands
+	if limit <= 0 {
+		limit = DefaultMaxBatchCommands
+	}
+	switch {
+	case len(entries) == 0:
+		Error(ctx, NewError(http.StatusBadRequest, errors.New("empty batch")))
+		return
+	case len(entries) > limit:
+		Error(ctx, NewError(
+			http.StatusBadRequest,
+			fmt.Errorf("batches are limited to %d commands", limit),
+		))
+		return
+	}
+
+	c, cancel := context.WithCancel(c)
+	defer cancel()
+	results := make([]batchResult, len(entries))
+	run := func(i int) {
+		if opts.StopOnError && c.Err() != nil {
+			results[i] = failedEntry(ErrBatchSkipped)
+			return
+		}
+		results[i] = runEntry(c, entries[i], handlers)
+		if opts.StopOnError && results[i].Error != "" {
+			cancel()
+		}
+	}
+	if opts.Parallel {
+		wg := sync.WaitGroup{}
+		for i := range entries {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				run(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range entries {
+			run(i)
+		}
+	}
+
+	for _, result := range results {
+		if result.err != nil {
+			ctx.Error(result.err)
+		}
+	}
+	body, err := json.Marshal(results)
+	if err != nil {
+		Error(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+	ctx.Header("Content-Type", MediaTypeJSON)
+	if _, err := ctx.Writer.Write(body); err != nil {
+		Error(ctx, err)
+	}
+}
+
+// runEntry runs a command of a batch, recovering from panics so that they
+// do not take down concurrent commands
+func runEntry(
+	c context.Context,
+	entry batchEntry,
+	handlers map[string]BatchHandler,
+) (result batchResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = failedEntry(fmt.Errorf("%v", r))
+		}
+	}()
+	handler, ok := handlers[entry.Command]
+	if !ok {
+		return failedEntry(ErrUnknownCommand)
+	}
+	res, err := handler(c, func(msg proto.Message) error {
+		if len(entry.Payload) == 0 {
+			return nil
+		}
+		if err := resultCodec.Unmarshal(entry.Payload, msg); err != nil {
+			return NewError(http.StatusBadRequest, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return failedEntry(err)
+	}
+	raw, err := resultCodec.Marshal(res)
+	if err != nil {
+		return failedEntry(err)
+	}
+	return batchResult{Status: http.StatusOK, Result: raw}
+}
+
+// failedEntry is the result of a command that failed with err, the message
+// of errors of unknown status being hidden
+func failedEntry(err error) batchResult {
+	if status := StatusOf(err); status != 0 {
+		return batchResult{Status: status, Error: err.Error()}
+	}
+	return batchResult{
+		Status: http.StatusInternalServerError,
+		Error:  http.StatusText(http.StatusInternalServerError),
+		err:    err,
+	}
+}
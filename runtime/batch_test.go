@@ -0,0 +1,174 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestServeBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handlers := map[string]BatchHandler{
+		"Svc.Echo": func(c context.Context, decode func(proto.Message) error) (proto.Message, error) {
+			in := &wrapperspb.StringValue{}
+			if err := decode(in); err != nil {
+				return nil, err
+			}
+			return in, nil
+		},
+		"Svc.Conflict": func(context.Context, func(proto.Message) error) (proto.Message, error) {
+			return nil, NewError(http.StatusConflict, errors.New("conflict"))
+		},
+		"Svc.Fail": func(context.Context, func(proto.Message) error) (proto.Message, error) {
+			return nil, errors.New("database is down")
+		},
+		"Svc.Panic": func(context.Context, func(proto.Message) error) (proto.Message, error) {
+			panic("boom")
+		},
+		"Svc.Guarded": func(context.Context, func(proto.Message) error) (proto.Message, error) {
+			return nil, ErrBatchMiddleware
+		},
+	}
+	tests := []struct {
+		name     string
+		opts     BatchOptions
+		body     string
+		status   int
+		statuses []int
+		errors   int
+	}{
+		{
+			name:     "sequential",
+			body:     `[{"command":"Svc.Echo","payload":"a"},{"command":"Svc.Conflict"},{"command":"Svc.Echo"}]`,
+			status:   http.StatusOK,
+			statuses: []int{http.StatusOK, http.StatusConflict, http.StatusOK},
+		},
+		{
+			name:     "parallel",
+			opts:     BatchOptions{Parallel: true},
+			body:     `[{"command":"Svc.Echo","payload":"a"},{"command":"Svc.Conflict"},{"command":"Svc.Echo"}]`,
+			status:   http.StatusOK,
+			statuses: []int{http.StatusOK, http.StatusConflict, http.StatusOK},
+		},
+		{
+			name:     "stop on error",
+			opts:     BatchOptions{StopOnError: true},
+			body:     `[{"command":"Svc.Echo"},{"command":"Svc.Conflict"},{"command":"Svc.Echo"}]`,
+			status:   http.StatusOK,
+			statuses: []int{http.StatusOK, http.StatusConflict, http.StatusFailedDependency},
+		},
+		{
+			name:     "invalid payload",
+			body:     `[{"command":"Svc.Echo","payload":1}]`,
+			status:   http.StatusOK,
+			statuses: []int{http.StatusBadRequest},
+		},
+		{
+			name:     "unknown command",
+			body:     `[{"command":"Svc.Other"}]`,
+			status:   http.StatusOK,
+			statuses: []int{http.StatusBadRequest},
+		},
+		{
+			name:     "command with route middlewares",
+			body:     `[{"command":"Svc.Guarded"}]`,
+			status:   http.StatusOK,
+			statuses: []int{http.StatusBadRequest},
+		},
+		{
+			name:     "errors of unknown status",
+			opts:     BatchOptions{Parallel: true},
+			body:     `[{"command":"Svc.Fail"},{"command":"Svc.Panic"}]`,
+			status:   http.StatusOK,
+			statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			errors:   2,
+		},
+		{
+			name:   "too many commands",
+			opts:   BatchOptions{MaxCommands: 1},
+			body:   `[{"command":"Svc.Echo"},{"command":"Svc.Echo"}]`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "empty batch",
+			body:   `[]`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "malformed batch",
+			body:   `{`,
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			ServeBatch(ctx, context.Background(), tt.opts, DecodeOptions{}, handlers)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.statuses == nil {
+				return
+			}
+			results := []batchResult{}
+			if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+				t.Fatal(err)
+			}
+			statuses := []int{}
+			for _, result := range results {
+				statuses = append(statuses, result.Status)
+				if result.Status == http.StatusInternalServerError &&
+					result.Error != http.StatusText(http.StatusInternalServerError) {
+					t.Errorf("error %q not hidden", result.Error)
+				}
+			}
+			if !reflect.DeepEqual(statuses, tt.statuses) {
+				t.Errorf("statuses = %v, want %v", statuses, tt.statuses)
+			}
+			if len(ctx.Errors) != tt.errors {
+				t.Errorf("%d errors left to the middlewares, want %d", len(ctx.Errors), tt.errors)
+			}
+		})
+	}
+}
+
+func TestServeBatchStopOnErrorCancels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handlers := map[string]BatchHandler{
+		"Svc.Conflict": func(context.Context, func(proto.Message) error) (proto.Message, error) {
+			return nil, NewError(http.StatusConflict, errors.New("conflict"))
+		},
+		"Svc.Wait": func(c context.Context, _ func(proto.Message) error) (proto.Message, error) {
+			<-c.Done()
+			return nil, NewError(http.StatusServiceUnavailable, c.Err())
+		},
+	}
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	body := `[{"command":"Svc.Wait"},{"command":"Svc.Conflict"}]`
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	opts := BatchOptions{Parallel: true, StopOnError: true}
+	ServeBatch(ctx, context.Background(), opts, DecodeOptions{}, handlers)
+	results := []batchResult{}
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].Status != http.StatusConflict {
+		t.Fatalf("results = %+v", results)
+	}
+	// the waiting command either saw the cancellation or was skipped
+	if s := results[0].Status; s != http.StatusServiceUnavailable && s != http.StatusFailedDependency {
+		t.Errorf("status of the cancelled command = %d", s)
+	}
+}
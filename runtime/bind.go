@@ -0,0 +1,145 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Sources of the bindings of input fields
+const (
+	InHeader = "header"
+	InCookie = "cookie"
+	InQuery  = "query"
+)
+
+// Binding binds a field of the input of an rpc to a request header, cookie
+// or query parameter
+type Binding struct {
+	// Field is the name of the bound field
+	Field protoreflect.Name
+	// In is InHeader, InCookie or InQuery
+	In string
+	// Name is the name of the header, cookie or query parameter
+	Name string
+	// Required fails requests without the header, cookie or query parameter
+	Required bool
+}
+
+// Bind sets the fields of msg from the headers, cookies and query parameters
+// of a request they are bound to, replacing the values of the body. Lists
+// are set from the repeated values of their query parameter. Fields whose
+// header, cookie or query parameter is missing are cleared, failing with 400
+// when it is required or cannot be converted to the type of the field.
+func Bind(ctx *gin.Context, msg proto.Message, bindings ...Binding) error {
+	m := msg.ProtoReflect()
+	for _, binding := range bindings {
+		fd := m.Descriptor().Fields().ByName(binding.Field)
+		if fd == nil {
+			return fmt.Errorf("%s has no field %s", m.Descriptor().FullName(), binding.Field)
+		}
+		m.Clear(fd)
+
+		var values []string
+		switch binding.In {
+		case InHeader:
+			if value := ctx.GetHeader(binding.Name); value != "" {
+				values = append(values, value)
+			}
+		case InCookie:
+			cookie, err := ctx.Request.Cookie(binding.Name)
+			if err == nil && cookie.Value != "" {
+				values = append(values, cookie.Value)
+			}
+		case InQuery:
+			for _, value := range ctx.QueryArray(binding.Name) {
+				if value != "" {
+					values = append(values, value)
+				}
+			}
+			if !fd.IsList() && len(values) > 1 {
+				return NewError(
+					http.StatusBadRequest,
+					fmt.Errorf("query parameter %s is repeated", binding.Name),
+				)
+			}
+		default:
+			return fmt.Errorf("field %s cannot be bound to a %s", fd.FullName(), binding.In)
+		}
+		if fd.IsMap() || fd.IsList() && binding.In != InQuery {
+			return fmt.Errorf("field %s cannot be bound to a %s", fd.FullName(), binding.In)
+		}
+		if len(values) == 0 {
+			if binding.Required {
+				return NewError(
+					http.StatusBadRequest,
+					fmt.Errorf("missing required %s %s", binding.In, binding.Name),
+				)
+			}
+			continue
+		}
+
+		for _, value := range values {
+			v, err := parseValue(fd, value)
+			if err != nil {
+				return NewError(
+					http.StatusBadRequest,
+					fmt.Errorf("invalid %s %s: %w", binding.In, binding.Name, err),
+				)
+			}
+			if fd.IsList() {
+				m.Mutable(fd).List().Append(v)
+			} else {
+				m.Set(fd, v)
+			}
+		}
+	}
+	return nil
+}
+
+// parseValue converts the text of a header, cookie, query parameter or entity
+// tag to the value of a scalar field
+func parseValue(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(s)), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(s, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, errors.New("unknown enum value")
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("field %s is not a scalar", fd.FullName())
+}
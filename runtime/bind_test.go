@@ -0,0 +1,179 @@
+package runtime
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestBind(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		msg     proto.Message
+		binding Binding
+		header  string
+		cookie  string
+		query   string
+		want    proto.Message
+		status  int
+		err     bool
+	}{
+		{
+			name:    "header",
+			msg:     wrapperspb.String("body"),
+			binding: Binding{Field: "value", In: InHeader, Name: "X-Value"},
+			header:  "header",
+			want:    wrapperspb.String("header"),
+		},
+		{
+			name:    "cookie",
+			msg:     wrapperspb.String("body"),
+			binding: Binding{Field: "value", In: InCookie, Name: "value"},
+			cookie:  "cookie",
+			want:    wrapperspb.String("cookie"),
+		},
+		{
+			name:    "missing clears the body",
+			msg:     wrapperspb.String("body"),
+			binding: Binding{Field: "value", In: InHeader, Name: "X-Value"},
+			want:    &wrapperspb.StringValue{},
+		},
+		{
+			name:    "missing required",
+			msg:     wrapperspb.String("body"),
+			binding: Binding{Field: "value", In: InCookie, Name: "value", Required: true},
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "integer",
+			msg:     &wrapperspb.Int64Value{},
+			binding: Binding{Field: "value", In: InHeader, Name: "X-Value"},
+			header:  "-7",
+			want:    wrapperspb.Int64(-7),
+		},
+		{
+			name:    "invalid integer",
+			msg:     &wrapperspb.UInt32Value{},
+			binding: Binding{Field: "value", In: InHeader, Name: "X-Value"},
+			header:  "-7",
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "bool",
+			msg:     &wrapperspb.BoolValue{},
+			binding: Binding{Field: "value", In: InHeader, Name: "X-Value"},
+			header:  "true",
+			want:    wrapperspb.Bool(true),
+		},
+		{
+			name:    "enum name",
+			msg:     &descriptorpb.FieldDescriptorProto{},
+			binding: Binding{Field: "type", In: InHeader, Name: "X-Type"},
+			header:  "TYPE_STRING",
+			want:    &descriptorpb.FieldDescriptorProto{Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+		},
+		{
+			name:    "enum number",
+			msg:     &descriptorpb.FieldDescriptorProto{},
+			binding: Binding{Field: "type", In: InHeader, Name: "X-Type"},
+			header:  "9",
+			want:    &descriptorpb.FieldDescriptorProto{Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+		},
+		{
+			name:    "unknown enum",
+			msg:     &descriptorpb.FieldDescriptorProto{},
+			binding: Binding{Field: "type", In: InHeader, Name: "X-Type"},
+			header:  "TYPE_UNKNOWN",
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "unknown field",
+			msg:     &wrapperspb.StringValue{},
+			binding: Binding{Field: "other", In: InHeader, Name: "X-Value"},
+			err:     true,
+		},
+		{
+			name:    "query",
+			msg:     wrapperspb.String("body"),
+			binding: Binding{Field: "value", In: InQuery, Name: "value"},
+			query:   "value=query",
+			want:    wrapperspb.String("query"),
+		},
+		{
+			name:    "empty query",
+			msg:     wrapperspb.String("body"),
+			binding: Binding{Field: "value", In: InQuery, Name: "value"},
+			query:   "value=",
+			want:    &wrapperspb.StringValue{},
+		},
+		{
+			name:    "repeated query",
+			msg:     &wrapperspb.StringValue{},
+			binding: Binding{Field: "value", In: InQuery, Name: "value"},
+			query:   "value=a&value=b",
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "list",
+			msg:     &descriptorpb.EnumDescriptorProto{ReservedName: []string{"body"}},
+			binding: Binding{Field: "reserved_name", In: InQuery, Name: "reservedName"},
+			query:   "reservedName=a&reservedName=b",
+			want:    &descriptorpb.EnumDescriptorProto{ReservedName: []string{"a", "b"}},
+		},
+		{
+			name:    "invalid list",
+			msg:     &descriptorpb.SourceCodeInfo_Location{},
+			binding: Binding{Field: "path", In: InQuery, Name: "path"},
+			query:   "path=1&path=x",
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "list in header",
+			msg:     &descriptorpb.EnumDescriptorProto{},
+			binding: Binding{Field: "reserved_name", In: InHeader, Name: "X-Value"},
+			header:  "a",
+			err:     true,
+		},
+		{
+			name:    "unknown source",
+			msg:     &wrapperspb.StringValue{},
+			binding: Binding{Field: "value", In: "path", Name: "value"},
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/?"+tt.query, nil)
+			if tt.header != "" {
+				ctx.Request.Header.Set(tt.binding.Name, tt.header)
+			}
+			if tt.cookie != "" {
+				ctx.Request.AddCookie(&http.Cookie{Name: tt.binding.Name, Value: tt.cookie})
+			}
+			err := Bind(ctx, tt.msg, tt.binding)
+			var httpErr *HTTPError
+			switch {
+			case tt.status != 0:
+				if !errors.As(err, &httpErr) || httpErr.Status != tt.status {
+					t.Errorf("err = %v, want status %d", err, tt.status)
+				}
+			case tt.err:
+				if err == nil || StatusOf(err) != 0 {
+					t.Errorf("err = %v, want an error of unknown status", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case !proto.Equal(tt.msg, tt.want):
+				t.Errorf("message = %v, want %v", tt.msg, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package runtime
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// ErrRequestTooLarge is returned when the body of a request, or its
+	// decoded content, exceeds the size limit of the rpc
+	ErrRequestTooLarge = NewError(
+		http.StatusRequestEntityTooLarge,
+		errors.New("request body too large"),
+	)
+	// ErrUnsupportedEncoding is returned when the body of a request is
+	// encoded with an unsupported content coding
+	ErrUnsupportedEncoding = NewError(
+		http.StatusUnsupportedMediaType,
+		errors.New("unsupported content encoding"),
+	)
+)
+
+// DecodeOptions configures how the body of a request is read
+type DecodeOptions struct {
+	// MaxBodyBytes limits the size of the body, both as received and once
+	// decoded, no limit being applied when it is zero or negative
+	MaxBodyBytes int64
+}
+
+// ReadBody reads the body of a request, undoing its gzip, br or zstd
+// content encoding and enforcing the size limit
+func ReadBody(ctx *gin.Context, opts DecodeOptions) ([]byte, error) {
+	limit := opts.MaxBodyBytes
+	if limit > 0 && ctx.Request.ContentLength > limit {
+		return nil, ErrRequestTooLarge
+	}
+
+	source := &sourceReader{r: ctx.Request.Body}
+	var body io.Reader = source
+	if limit > 0 {
+		body = &limitedReader{r: body, n: limit}
+	}
+	decoded, err := decompress(strings.ToLower(strings.TrimSpace(
+		ctx.GetHeader("Content-Encoding"),
+	)), body, limit)
+	if err != nil {
+		return nil, readError(err, source)
+	}
+	defer decoded.Close()
+	body = decoded
+	if limit > 0 {
+		body = &limitedReader{r: body, n: limit}
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, readError(err, source)
+	}
+	return raw, nil
+}
+
+// readError reports failures to decode the content of a body as bad
+// requests, keeping size limit errors as is and passing on the failures to
+// read the body itself
+func readError(err error, source *sourceReader) error {
+	switch {
+	case errors.Is(err, ErrRequestTooLarge),
+		errors.Is(err, zstd.ErrDecoderSizeExceeded),
+		errors.Is(err, zstd.ErrWindowSizeExceeded):
+		return ErrRequestTooLarge
+	case errors.Is(err, ErrUnsupportedEncoding):
+		return ErrUnsupportedEncoding
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		// the body is truncated, or its content coding is
+		return NewError(http.StatusBadRequest, err)
+	case source.err != nil && errors.Is(err, source.err):
+		return err
+	}
+	return NewError(http.StatusBadRequest, err)
+}
+
+// sourceReader keeps the error of the body of a request, telling it apart
+// from those of the decoder of its content
+type sourceReader struct {
+	r   io.Reader
+	err error
+}
+
+func (s *sourceReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err != nil && err != io.EOF {
+		s.err = err
+	}
+	return n, err
+}
+
+// limitedReader fails with ErrRequestTooLarge once more than n bytes are
+// read, unlike io.LimitReader which silently truncates
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrRequestTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrRequestTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,155 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+func zstdEncoded(t *testing.T, raw []byte, window int) []byte {
+	t.Helper()
+	zw, err := zstd.NewWriter(nil, zstd.WithWindowSize(window))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zw.EncodeAll(raw, nil)
+}
+
+func TestReadBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	small := []byte(`{"id":"a"}`)
+	large := bytes.Repeat([]byte("a"), 1<<20)
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		limit    int64
+		want     []byte
+		status   int
+	}{
+		{
+			name: "identity",
+			body: small,
+			want: small,
+		},
+		{
+			name:   "identity too large",
+			body:   large,
+			limit:  4096,
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "gzip",
+			encoding: EncodingGzip,
+			body:     gzipped(t, string(small)),
+			limit:    4096,
+			want:     small,
+		},
+		{
+			name:     "gzip decoded too large",
+			encoding: EncodingGzip,
+			body:     gzipped(t, string(large)),
+			limit:    4096,
+			status:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "zstd",
+			encoding: EncodingZstd,
+			body:     zstdEncoded(t, small, zstd.MinWindowSize),
+			limit:    4096,
+			want:     small,
+		},
+		{
+			name:     "zstd window larger than limit",
+			encoding: EncodingZstd,
+			body:     zstdEncoded(t, large, 1<<20),
+			limit:    4096,
+			status:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "zstd without limit",
+			encoding: EncodingZstd,
+			body:     zstdEncoded(t, large, 1<<20),
+			want:     large,
+		},
+		{
+			name:     "corrupt gzip",
+			encoding: EncodingGzip,
+			body:     append(gzipped(t, string(small))[:12], "garbage"...),
+			status:   http.StatusBadRequest,
+		},
+		{
+			name:     "corrupt brotli",
+			encoding: EncodingBrotli,
+			body:     []byte("garbage that is not brotli"),
+			status:   http.StatusBadRequest,
+		},
+		{
+			name:     "corrupt zstd",
+			encoding: EncodingZstd,
+			body:     []byte("garbage that is not zstd"),
+			status:   http.StatusBadRequest,
+		},
+		{
+			name:     "truncated zstd",
+			encoding: EncodingZstd,
+			body:     zstdEncoded(t, large, 1<<20)[:20],
+			status:   http.StatusBadRequest,
+		},
+		{
+			name:     "unsupported encoding",
+			encoding: "compress",
+			body:     small,
+			status:   http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			ctx.Request.ContentLength = -1
+			ctx.Request.Header.Set("Content-Encoding", tt.encoding)
+			raw, err := ReadBody(ctx, DecodeOptions{MaxBodyBytes: tt.limit})
+			if tt.status != 0 {
+				var httpErr *HTTPError
+				if !errors.As(err, &httpErr) || httpErr.Status != tt.status {
+					t.Fatalf("err = %v, want status %d", err, tt.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(raw, tt.want) {
+				t.Errorf("body of %d bytes, want %d", len(raw), len(tt.want))
+			}
+		})
+	}
+}
+
+// failingReader fails to read the body of a request
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestReadBodySourceError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, encoding := range []string{"", EncodingGzip, EncodingBrotli, EncodingZstd} {
+		t.Run(encoding, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", failingReader{})
+			ctx.Request.Header.Set("Content-Encoding", encoding)
+			_, err := ReadBody(ctx, DecodeOptions{MaxBodyBytes: 4096})
+			if err == nil || StatusOf(err) == http.StatusBadRequest {
+				t.Errorf("err = %v, want the error of the body", err)
+			}
+		})
+	}
+}
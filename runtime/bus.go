@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrUnknownCommand is returned when dispatching a command no rpc
+	// takes, or one that cannot be batched
+	ErrUnknownCommand = NewError(
+		http.StatusBadRequest,
+		errors.New("unknown command"),
+	)
+	// ErrUnknownQuery is returned when dispatching a query no rpc takes
+	ErrUnknownQuery = NewError(
+		http.StatusBadRequest,
+		errors.New("unknown query"),
+	)
+)
+
+// Handler handles a command or query
+type Handler func(c context.Context, req proto.Message) (proto.Message, error)
+
+// Behavior wraps the dispatch of the commands or queries of a bus, such as
+// for logging, validation or transactions, calling next to carry on. The
+// method is the full name of the rpc being dispatched, and next must be
+// given a request of the type it was given.
+type Behavior func(
+	c context.Context,
+	method string,
+	req proto.Message,
+	next Handler,
+) (proto.Message, error)
+
+// Dispatch runs handler for the rpc method through behaviors, the first
+// being the outermost
+func Dispatch(
+	c context.Context,
+	behaviors []Behavior,
+	method string,
+	req proto.Message,
+	handler Handler,
+) (proto.Message, error) {
+	next := handler
+	for i := len(behaviors) - 1; i >= 0; i-- {
+		behavior, inner := behaviors[i], next
+		next = func(c context.Context, req proto.Message) (proto.Message, error) {
+			return behavior(c, method, req, inner)
+		}
+	}
+	return next(c, req)
+}
@@ -0,0 +1,261 @@
+package runtime
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Media types supported by the generated handlers
+const (
+	MediaTypeJSON     = "application/json"
+	MediaTypeProtobuf = "application/x-protobuf"
+)
+
+var (
+	// ErrUnsupportedMediaType is returned when the body of a request is of
+	// a media type no codec supports
+	ErrUnsupportedMediaType = NewError(
+		http.StatusUnsupportedMediaType,
+		errors.New("unsupported media type"),
+	)
+	// ErrNotAcceptable is returned when no codec produces a media type the
+	// request accepts
+	ErrNotAcceptable = NewError(
+		http.StatusNotAcceptable,
+		errors.New("not acceptable"),
+	)
+)
+
+// Codec marshals messages to and from a media type
+type Codec interface {
+	MediaType() string
+	Marshal(msg proto.Message) ([]byte, error)
+	Unmarshal(raw []byte, msg proto.Message) error
+}
+
+// JSONCodec is the protojson codec, used when a request does not specify
+// a media type
+type JSONCodec struct {
+	MarshalOptions   protojson.MarshalOptions
+	UnmarshalOptions protojson.UnmarshalOptions
+}
+
+func (c JSONCodec) MediaType() string {
+	return MediaTypeJSON
+}
+
+func (c JSONCodec) Marshal(msg proto.Message) ([]byte, error) {
+	return c.MarshalOptions.Marshal(msg)
+}
+
+func (c JSONCodec) Unmarshal(raw []byte, msg proto.Message) error {
+	return c.UnmarshalOptions.Unmarshal(raw, msg)
+}
+
+// ProtobufCodec is the binary protobuf codec
+type ProtobufCodec struct{}
+
+func (c ProtobufCodec) MediaType() string {
+	return MediaTypeProtobuf
+}
+
+// Marshal is deterministic so that equal messages get equal ETags
+func (c ProtobufCodec) Marshal(msg proto.Message) ([]byte, error) {
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
+
+func (c ProtobufCodec) Unmarshal(raw []byte, msg proto.Message) error {
+	return proto.Unmarshal(raw, msg)
+}
+
+// Codecs are the codecs supported by the generated handlers, the first being
+// the default
+var Codecs = []Codec{
+	JSONCodec{MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true}},
+	ProtobufCodec{},
+}
+
+// mediaTypeAliases maps alternative names of media types to the ones of the
+// codecs
+var mediaTypeAliases = map[string]string{
+	"application/protobuf":            MediaTypeProtobuf,
+	"application/x-protobuf":          MediaTypeProtobuf,
+	"application/vnd.google.protobuf": MediaTypeProtobuf,
+}
+
+func codecFor(mediaType string) Codec {
+	if alias, ok := mediaTypeAliases[mediaType]; ok {
+		mediaType = alias
+	}
+	for _, codec := range Codecs {
+		if codec.MediaType() == mediaType {
+			return codec
+		}
+	}
+	return nil
+}
+
+// Negotiate picks the codec of the response from the Accept header of a
+// request, failing with 406 when none is acceptable
+func Negotiate(ctx *gin.Context) (Codec, error) {
+	accept := ctx.GetHeader("Accept")
+	if accept == "" {
+		return Codecs[0], nil
+	}
+
+	type candidate struct {
+		mediaType string
+		quality   float64
+	}
+	candidates := []candidate{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{mediaType, quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, cand := range candidates {
+		switch cand.mediaType {
+		case "*/*", "application/*":
+			return Codecs[0], nil
+		}
+		if codec := codecFor(cand.mediaType); codec != nil {
+			return codec, nil
+		}
+	}
+	return nil, ErrNotAcceptable
+}
+
+// Decode reads the body of a request into msg with the codec of its
+// Content-Type, failing with 415 when no codec supports it, with 413 when
+// the body is too large and with 400 when it is not a valid message
+func Decode(ctx *gin.Context, msg proto.Message, opts DecodeOptions) error {
+	codec := Codecs[0]
+	if contentType := ctx.GetHeader("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return ErrUnsupportedMediaType
+		}
+		if codec = codecFor(mediaType); codec == nil {
+			return ErrUnsupportedMediaType
+		}
+	}
+
+	raw, err := ReadBody(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := codec.Unmarshal(raw, msg); err != nil {
+		return NewError(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+// EncodeOptions configures how the response of a request is written
+type EncodeOptions struct {
+	// Compression compresses the response body when set
+	Compression *Compression
+	// ETag sets a strong ETag on the response, answering 304 Not Modified
+	// to GET and HEAD requests when it matches their If-None-Match header,
+	// and 412 to requests of other methods as RFC 9110 requires
+	ETag bool
+	// ETagField is the field of the response used as its ETag, a hash of
+	// the marshalled response being used when empty
+	ETagField protoreflect.Name
+	// Request is the input of the rpc, hashed into the ETags built from
+	// ETagField
+	Request proto.Message
+	// CacheControl is the Cache-Control header of the response when set
+	CacheControl string
+	// HeaderFields are the fields of the response written as headers, and
+	// left out of its body
+	HeaderFields []HeaderField
+}
+
+// Encode writes msg as the response of a request with the negotiated codec
+// and status, without a body for 204 and 205
+func Encode(
+	ctx *gin.Context,
+	codec Codec,
+	status int,
+	msg proto.Message,
+	opts EncodeOptions,
+) error {
+	body, err := writeHeaderFields(ctx, msg, opts.HeaderFields)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNoContent || status == http.StatusResetContent {
+		ctx.Status(status)
+		return nil
+	}
+	raw, err := codec.Marshal(body)
+	if err != nil {
+		return err
+	}
+	if codec.MediaType() == MediaTypeJSON {
+		if raw, err = stripJSONFields(raw, body, opts.HeaderFields); err != nil {
+			return err
+		}
+	}
+	// the body depends on the negotiated media type
+	ctx.Writer.Header().Add("Vary", "Accept")
+	encoding := opts.Compression.encoding(ctx, len(raw))
+	if opts.CacheControl != "" {
+		ctx.Header("Cache-Control", opts.CacheControl)
+	}
+	if opts.ETag {
+		etag := EntityTag(
+			msg,
+			raw,
+			opts.ETagField,
+			opts.Request,
+			codec.MediaType(),
+			encoding,
+		)
+		ctx.Header("ETag", etag)
+		if matchesETag(ctx.GetHeader("If-None-Match"), etag, true) {
+			switch ctx.Request.Method {
+			case http.MethodGet, http.MethodHead:
+				ctx.Status(http.StatusNotModified)
+				return nil
+			}
+			return ErrPreconditionFailed
+		}
+	}
+	if encoding != "" {
+		if raw, err = compress(encoding, raw); err != nil {
+			return err
+		}
+		ctx.Header("Content-Encoding", encoding)
+	}
+	ctx.Status(status)
+	ctx.Header("Content-Type", codec.MediaType())
+	_, err = ctx.Writer.Write(raw)
+	return err
+}
@@ -0,0 +1,160 @@
+package runtime
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings supported for request and response bodies
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+)
+
+// Compression configures the compression of response bodies
+type Compression struct {
+	// Threshold is the size from which response bodies are compressed,
+	// smaller ones being sent as is
+	Threshold int
+	// Encodings are the content codings offered to clients in order of
+	// preference, defaulting to zstd, br and gzip
+	Encodings []string
+}
+
+var defaultEncodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+// zstdEncoder is shared by every response, EncodeAll being safe for
+// concurrent use
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+// decompress undoes the content coding of a body, the memory of the zstd
+// decoder being bounded by limit when it is positive
+func decompress(encoding string, body io.Reader, limit int64) (io.ReadCloser, error) {
+	switch encoding {
+	case "", "identity":
+		return io.NopCloser(body), nil
+	case EncodingGzip, "x-gzip":
+		return gzip.NewReader(body)
+	case EncodingBrotli:
+		return io.NopCloser(brotli.NewReader(body)), nil
+	case EncodingZstd:
+		options := []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+		if limit > 0 {
+			window := uint64(limit)
+			if window < zstd.MinWindowSize {
+				window = zstd.MinWindowSize
+			}
+			if window > zstd.MaxWindowSize {
+				window = zstd.MaxWindowSize
+			}
+			options = append(
+				options,
+				zstd.WithDecoderMaxMemory(uint64(limit)),
+				zstd.WithDecoderMaxWindow(window),
+			)
+		}
+		zr, err := zstd.NewReader(body, options...)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, ErrUnsupportedEncoding
+}
+
+// compress applies a content coding to a response body
+func compress(encoding string, raw []byte) ([]byte, error) {
+	if encoding == EncodingZstd {
+		return zstdEncoder.EncodeAll(raw, nil), nil
+	}
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingBrotli:
+		w = brotli.NewWriter(&buf)
+	default:
+		return raw, nil
+	}
+	if _, err := w.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// acceptedEncoding picks the content coding of a response from the
+// Accept-Encoding header of a request, an empty string meaning none
+func (c *Compression) acceptedEncoding(ctx *gin.Context) string {
+	accepted := acceptedEncodings(ctx)
+	encodings := c.Encodings
+	if len(encodings) == 0 {
+		encodings = defaultEncodings
+	}
+	for _, encoding := range encodings {
+		quality, ok := accepted[encoding]
+		if !ok {
+			quality, ok = accepted["*"]
+		}
+		if ok && quality > 0 {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// acceptedEncodings parses the Accept-Encoding header of a request into
+// the quality of each content coding
+func acceptedEncodings(ctx *gin.Context) map[string]float64 {
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(ctx.GetHeader("Accept-Encoding"), ",") {
+		coding, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		accepted[coding] = quality
+	}
+	return accepted
+}
+
+// acceptsEncoding reports whether a request accepts a content coding
+func acceptsEncoding(ctx *gin.Context, encoding string) bool {
+	accepted := acceptedEncodings(ctx)
+	quality, ok := accepted[encoding]
+	if !ok {
+		quality, ok = accepted["*"]
+	}
+	return ok && quality > 0
+}
+
+// encoding picks the content coding of a response body of the given size,
+// an empty string meaning it is sent as is
+func (c *Compression) encoding(ctx *gin.Context, size int) string {
+	if c == nil {
+		return ""
+	}
+	ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+	if size < c.Threshold {
+		return ""
+	}
+	return c.acceptedEncoding(ctx)
+}
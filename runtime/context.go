@@ -0,0 +1,76 @@
+package runtime
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gin keys of the values set through this package, namespaced so that they
+// can not collide with the keys of other middlewares
+const (
+	contextKey   = "techunicorn.com/protoc-gen-gocqrshttp/runtime.context"
+	principalKey = "techunicorn.com/protoc-gen-gocqrshttp/runtime.principal"
+)
+
+// LegacyContextKey is the gin key generated code used to read the
+// application context from.
+//
+// Deprecated: use SetContext, or a ContextFactory.
+const LegacyContextKey = "inCxt"
+
+type principalContextKey struct{}
+
+// ContextFactory builds the context handed to the application from a
+// request, carrying its deadline, cancellation, trace and principal
+type ContextFactory func(ctx *gin.Context) (context.Context, error)
+
+// SetContext sets the context handed to the application for a request, for
+// middlewares preparing it ahead of the generated handlers
+func SetContext(ctx *gin.Context, c context.Context) {
+	ctx.Set(contextKey, c)
+}
+
+// SetPrincipal sets the authenticated caller of a request, for
+// authentication middlewares
+func SetPrincipal(ctx *gin.Context, principal interface{}) {
+	ctx.Set(principalKey, principal)
+}
+
+// WithPrincipal returns a copy of c carrying the authenticated caller
+func WithPrincipal(c context.Context, principal interface{}) context.Context {
+	return context.WithValue(c, principalContextKey{}, principal)
+}
+
+// PrincipalFrom returns the authenticated caller carried by c
+func PrincipalFrom(c context.Context) (interface{}, bool) {
+	principal := c.Value(principalContextKey{})
+	return principal, principal != nil
+}
+
+// DefaultContextFactory hands the application the context set with
+// SetContext, falling back to the context of the request, along with the
+// principal set with SetPrincipal
+func DefaultContextFactory(ctx *gin.Context) (context.Context, error) {
+	c := ctx.Request.Context()
+	if v, ok := ctx.Get(contextKey); ok {
+		c = v.(context.Context)
+	} else if v, ok := ctx.Get(LegacyContextKey); ok {
+		if legacy, ok := v.(context.Context); ok {
+			c = legacy
+		}
+	}
+	if principal, ok := ctx.Get(principalKey); ok {
+		c = WithPrincipal(c, principal)
+	}
+	return c, nil
+}
+
+// Context builds the context handed to the application with factory, or
+// DefaultContextFactory when it is nil
+func Context(ctx *gin.Context, factory ContextFactory) (context.Context, error) {
+	if factory == nil {
+		factory = DefaultContextFactory
+	}
+	return factory(ctx)
+}
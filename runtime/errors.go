@@ -0,0 +1,62 @@
+package runtime
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPError is an error rendered with a specific http status
+type HTTPError struct {
+	Status int
+	Err    error
+}
+
+// NewError wraps err to be rendered with status
+func NewError(status int, err error) *HTTPError {
+	return &HTTPError{Status: status, Err: err}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// StatusOf returns the http status of an error, either an HTTPError, an
+// error implementing HTTPStatus() int, or one of the errors of this package.
+// Zero is returned for any other error, leaving the status to the error
+// handling middlewares.
+func StatusOf(err error) int {
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		return herr.Status
+	}
+	var serr interface{ HTTPStatus() int }
+	if errors.As(err, &serr) {
+		return serr.HTTPStatus()
+	}
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNoAuthorizer):
+		return http.StatusInternalServerError
+	}
+	return 0
+}
+
+// Error aborts a request with err through the standard gin error path,
+// setting its status when known
+func Error(ctx *gin.Context, err error) {
+	if status := StatusOf(err); status != 0 {
+		ctx.AbortWithError(status, err)
+		return
+	}
+	ctx.Abort()
+	ctx.Error(err)
+}
@@ -0,0 +1,161 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrPreconditionFailed is returned by the application when the expected
+// version of a command does not match the current version of its aggregate
+var ErrPreconditionFailed = NewError(
+	http.StatusPreconditionFailed,
+	errors.New("precondition failed"),
+)
+
+// EntityTag computes the strong ETag of a response from the value of its
+// version field, or from a hash of its marshalled body when field is empty.
+// The opaque tag joins with dots the base64url encoded version and a hash of
+// req, the input of the rpc, and of the media type of the body, for the
+// responses of different queries or in different media types of one version
+// to differ, followed by the content coding of the body when it is
+// compressed.
+func EntityTag(
+	msg proto.Message,
+	raw []byte,
+	field protoreflect.Name,
+	req proto.Message,
+	mediaType string,
+	encoding string,
+) string {
+	var parts []string
+	if field != "" {
+		m := msg.ProtoReflect()
+		fd := m.Descriptor().Fields().ByName(field)
+		if fd != nil {
+			if version := fmt.Sprint(m.Get(fd).Interface()); version != "" {
+				parts = append(
+					parts,
+					base64.RawURLEncoding.EncodeToString([]byte(version)),
+					requestHash(req, mediaType),
+				)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		sum := sha256.Sum256(raw)
+		parts = append(parts, base64.RawURLEncoding.EncodeToString(sum[:16]))
+	}
+	if encoding != "" {
+		parts = append(parts, encoding)
+	}
+	return `"` + strings.Join(parts, etagSeparator) + `"`
+}
+
+// etagSeparator separates the parts of an entity tag, it is left out of the
+// base64url alphabet of the versions and hashes
+const etagSeparator = "."
+
+// requestHash hashes the input of an rpc and the media type of its response
+func requestHash(req proto.Message, mediaType string) string {
+	h := sha256.New()
+	h.Write([]byte(mediaType + "\n"))
+	if req != nil {
+		raw, _ := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+		h.Write(raw)
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:8])
+}
+
+// entityTagVersion extracts the version of an entity tag, the tag itself
+// when it is a bare version without separator
+func entityTagVersion(tag string) (string, error) {
+	i := strings.Index(tag, etagSeparator)
+	if i < 0 {
+		return tag, nil
+	}
+	version, err := base64.RawURLEncoding.DecodeString(tag[:i])
+	if err != nil {
+		return "", err
+	}
+	return string(version), nil
+}
+
+// matchesETag reports whether an If-None-Match or If-Match header matches
+// etag, weak comparison ignoring the W/ prefix of the tags
+func matchesETag(header string, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// IfMatch sets the expected version field of a command from the If-Match
+// header of the request, leaving it as decoded when the header is missing or
+// is *. The tag is the ETag of a query response or the bare version, which
+// cannot hold a dot. Weak tags never match and fail with 412, as do tags
+// that are not a valid value of the field, while lists of tags and malformed
+// tags fail with 400.
+func IfMatch(ctx *gin.Context, msg proto.Message, field protoreflect.Name) error {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+	if strings.Contains(header, ",") {
+		return NewError(
+			http.StatusBadRequest,
+			errors.New("If-Match must carry a single entity tag"),
+		)
+	}
+	if strings.HasPrefix(header, "W/") {
+		return ErrPreconditionFailed
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' ||
+		strings.Contains(header[1:len(header)-1], `"`) {
+		return NewError(
+			http.StatusBadRequest,
+			fmt.Errorf("malformed entity tag %s", header),
+		)
+	}
+	tag, err := entityTagVersion(header[1 : len(header)-1])
+	if err != nil {
+		return ErrPreconditionFailed
+	}
+
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(field)
+	if fd == nil {
+		return fmt.Errorf("%s has no field %s", m.Descriptor().FullName(), field)
+	}
+	switch fd.Kind() {
+	case protoreflect.StringKind,
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+	default:
+		return fmt.Errorf("field %s cannot hold a version", fd.FullName())
+	}
+	value, err := parseValue(fd, tag)
+	if err != nil {
+		return ErrPreconditionFailed
+	}
+	m.Set(fd, value)
+	return nil
+}
@@ -0,0 +1,168 @@
+package runtime
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEntityTag(t *testing.T) {
+	version := wrapperspb.Int64(7)
+	text := wrapperspb.String(`v"1 \`)
+	tests := []struct {
+		name  string
+		a, b  string
+		equal bool
+	}{
+		{
+			name:  "version with quotes",
+			a:     EntityTag(text, nil, "value", nil, MediaTypeJSON, "gzip"),
+			b:     EntityTag(text, nil, "value", nil, MediaTypeJSON, "gzip"),
+			equal: true,
+		},
+		{
+			name:  "same query",
+			a:     EntityTag(version, nil, "value", wrapperspb.String("q"), MediaTypeJSON, ""),
+			b:     EntityTag(version, nil, "value", wrapperspb.String("q"), MediaTypeJSON, ""),
+			equal: true,
+		},
+		{
+			name: "other query",
+			a:    EntityTag(version, nil, "value", wrapperspb.String("q"), MediaTypeJSON, ""),
+			b:    EntityTag(version, nil, "value", wrapperspb.String("r"), MediaTypeJSON, ""),
+		},
+		{
+			name: "other media type",
+			a:    EntityTag(version, nil, "value", wrapperspb.String("q"), MediaTypeJSON, ""),
+			b:    EntityTag(version, nil, "value", wrapperspb.String("q"), MediaTypeProtobuf, ""),
+		},
+		{
+			name: "other content coding",
+			a:    EntityTag(version, nil, "value", nil, MediaTypeJSON, ""),
+			b:    EntityTag(version, nil, "value", nil, MediaTypeJSON, "gzip"),
+		},
+		{
+			name:  "same body",
+			a:     EntityTag(version, []byte("a"), "", nil, MediaTypeJSON, ""),
+			b:     EntityTag(version, []byte("a"), "", nil, MediaTypeJSON, ""),
+			equal: true,
+		},
+		{
+			name: "other body",
+			a:    EntityTag(version, []byte("a"), "", nil, MediaTypeJSON, ""),
+			b:    EntityTag(version, []byte("b"), "", nil, MediaTypeJSON, ""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.a == tt.b) != tt.equal {
+				t.Errorf("%s and %s, want equal %v", tt.a, tt.b, tt.equal)
+			}
+			for _, tag := range []string{tt.a, tt.b} {
+				if !entityTag.MatchString(tag) {
+					t.Errorf("%s is not an entity tag", tag)
+				}
+			}
+		})
+	}
+}
+
+// entityTag matches the strong entity tags of RFC 9110
+var entityTag = regexp.MustCompile(`^"[\x21\x23-\x7e]*"$`)
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	etag := EntityTag(wrapperspb.Int64(7), nil, "value", wrapperspb.String("q"), MediaTypeJSON, "gzip")
+	tests := []struct {
+		header string
+		want   int64
+		status int
+	}{
+		{header: "", want: 1},
+		{header: "*", want: 1},
+		{header: `"7"`, want: 7},
+		{header: etag, want: 7},
+		{header: `"Nw.hash"`, want: 7},
+		{header: `W/"7"`, status: http.StatusPreconditionFailed},
+		{header: `"x"`, status: http.StatusPreconditionFailed},
+		{header: `"!.hash"`, status: http.StatusPreconditionFailed},
+		{header: `"1", "2"`, status: http.StatusBadRequest},
+		{header: `7`, status: http.StatusBadRequest},
+		{header: `"7"7"`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+			ctx.Request.Header.Set("If-Match", tt.header)
+			msg := wrapperspb.Int64(1)
+			err := IfMatch(ctx, msg, "value")
+			var httpErr *HTTPError
+			switch {
+			case tt.status != 0 && (!errors.As(err, &httpErr) || httpErr.Status != tt.status):
+				t.Fatalf("err = %v, want status %d", err, tt.status)
+			case tt.status == 0 && err != nil:
+				t.Fatal(err)
+			case tt.status == 0 && msg.Value != tt.want:
+				t.Errorf("version = %d, want %d", msg.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeConditional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := wrapperspb.Int64(7)
+	opts := EncodeOptions{
+		Compression: &Compression{Threshold: 1 << 20},
+		ETag:        true,
+		ETagField:   "value",
+		Request:     wrapperspb.String("q"),
+	}
+	etag := EntityTag(res, nil, "value", opts.Request, MediaTypeJSON, "")
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		status      int
+		err         error
+	}{
+		{name: "get without tag", method: http.MethodGet, status: http.StatusOK},
+		{name: "get matching", method: http.MethodGet, ifNoneMatch: etag, status: http.StatusNotModified},
+		{name: "get not matching", method: http.MethodGet, ifNoneMatch: `"8"`, status: http.StatusOK},
+		{name: "post matching", method: http.MethodPost, ifNoneMatch: etag, err: ErrPreconditionFailed},
+		{name: "post not matching", method: http.MethodPost, ifNoneMatch: `"8"`, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(tt.method, "/", nil)
+			ctx.Request.Header.Set("If-None-Match", tt.ifNoneMatch)
+			err := Encode(ctx, Codecs[0], http.StatusOK, proto.Clone(res), opts)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			ctx.Writer.WriteHeaderNow()
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag = %s, want %s", got, etag)
+			}
+			if got := strings.Join(w.Header().Values("Vary"), ", "); got != "Accept, Accept-Encoding" {
+				t.Errorf("Vary = %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key of a
+// command
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+var (
+	// ErrIdempotencyKeyMissing is returned when an idempotent command is
+	// sent without an idempotency key
+	ErrIdempotencyKeyMissing = NewError(
+		http.StatusBadRequest,
+		errors.New("missing "+IdempotencyKeyHeader+" header"),
+	)
+	// ErrIdempotencyInFlight is returned when a command is sent again while
+	// the first request with its key is still being handled
+	ErrIdempotencyInFlight = NewError(
+		http.StatusConflict,
+		errors.New("a request with this idempotency key is in flight"),
+	)
+	// ErrIdempotencyKeyReused is returned when an idempotency key is reused
+	// for a different command or payload
+	ErrIdempotencyKeyReused = NewError(
+		http.StatusUnprocessableEntity,
+		errors.New("idempotency key reused with a different payload"),
+	)
+	// ErrNoIdempotencyStore is returned when an rpc is idempotent but no
+	// store was registered
+	ErrNoIdempotencyStore = NewError(
+		http.StatusInternalServerError,
+		errors.New("no idempotency store registered"),
+	)
+)
+
+// IdempotencyRecord is what is known of a request under an idempotency key
+type IdempotencyRecord struct {
+	// Fingerprint identifies the rpc and payload of the request
+	Fingerprint string
+	// Completed is false while the request is in flight
+	Completed bool
+	Status    int
+	Header    http.Header
+	Body      []byte
+}
+
+// IdempotencyStore stores the responses of idempotent commands by key
+type IdempotencyStore interface {
+	// Begin claims a key for a request, returning the record of the key
+	// when it was already claimed, or nil when it was claimed by this call
+	Begin(
+		ctx context.Context,
+		key string,
+		fingerprint string,
+	) (*IdempotencyRecord, error)
+	// Complete stores the response of the request that claimed a key
+	Complete(ctx context.Context, key string, record IdempotencyRecord) error
+	// Release frees a key whose request failed so that it can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency tracks an idempotent request from BeginIdempotent to End
+type Idempotency struct {
+	store       IdempotencyStore
+	c           context.Context
+	key         string
+	fingerprint string
+	replayed    bool
+	recorder    *responseRecorder
+}
+
+// BeginIdempotent claims the idempotency key of a request for the rpc
+// method with payload req. When the key already completed, its stored
+// response is replayed and Replayed reports true. It fails with 409 while
+// the key is in flight and with 422 when it was used for another payload.
+func BeginIdempotent(
+	c context.Context,
+	ctx *gin.Context,
+	store IdempotencyStore,
+	method string,
+	req proto.Message,
+) (*Idempotency, error) {
+	if store == nil {
+		return nil, ErrNoIdempotencyStore
+	}
+	key := ctx.GetHeader(IdempotencyKeyHeader)
+	if key == "" {
+		return nil, ErrIdempotencyKeyMissing
+	}
+	// keys are scoped to the principal so that callers cannot replay the
+	// responses of one another
+	if principal, ok := PrincipalFrom(c); ok {
+		key = fmt.Sprint(principal) + "\x00" + key
+	}
+	raw, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	// the negotiated media type is part of the fingerprint, a replay
+	// having to be of the representation the request accepts. Async rpcs
+	// answer with json whatever is accepted.
+	mediaType := ""
+	if codec, err := Negotiate(ctx); err == nil {
+		mediaType = codec.MediaType()
+	}
+	sum := sha256.Sum256(append(
+		[]byte(method+"\n"+mediaType+"\n"),
+		raw...,
+	))
+	fingerprint := hex.EncodeToString(sum[:])
+
+	existing, err := store.Begin(c, key, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	idem := &Idempotency{
+		store:       store,
+		c:           c,
+		key:         key,
+		fingerprint: fingerprint,
+	}
+	if existing != nil {
+		switch {
+		case existing.Fingerprint != fingerprint:
+			return nil, ErrIdempotencyKeyReused
+		case !existing.Completed:
+			return nil, ErrIdempotencyInFlight
+		}
+		header, body, err := replayedResponse(ctx, existing)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range header {
+			ctx.Writer.Header()[name] = values
+		}
+		ctx.Header("Idempotent-Replayed", "true")
+		ctx.Status(existing.Status)
+		_, err = ctx.Writer.Write(body)
+		idem.replayed = true
+		return idem, err
+	}
+
+	idem.recorder = &responseRecorder{ResponseWriter: ctx.Writer}
+	ctx.Writer = idem.recorder
+	return idem, nil
+}
+
+// replayedResponse returns the stored response of a key, its content coding
+// undone when the request does not accept it
+func replayedResponse(
+	ctx *gin.Context,
+	record *IdempotencyRecord,
+) (http.Header, []byte, error) {
+	header := record.Header.Clone()
+	encoding := header.Get("Content-Encoding")
+	if encoding == "" || acceptsEncoding(ctx, encoding) {
+		return header, record.Body, nil
+	}
+	decoded, err := decompress(encoding, bytes.NewReader(record.Body), 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer decoded.Close()
+	body, err := io.ReadAll(decoded)
+	if err != nil {
+		return nil, nil, err
+	}
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	return header, body, nil
+}
+
+// Replayed reports whether the stored response of the key was replayed, in
+// which case the request must not be handled again
+func (i *Idempotency) Replayed() bool {
+	return i.replayed
+}
+
+// End stores the response of the request, or releases its key when it
+// failed or panicked so that it can be retried. It must be deferred for
+// panics to be seen, which it carries on.
+func (i *Idempotency) End(ctx *gin.Context) {
+	if i.replayed {
+		return
+	}
+	ctx.Writer = i.recorder.ResponseWriter
+	if p := recover(); p != nil {
+		if err := i.store.Release(i.c, i.key); err != nil {
+			ctx.Error(err)
+		}
+		panic(p)
+	}
+	status := i.recorder.Status()
+	if len(ctx.Errors) != 0 || status >= http.StatusInternalServerError {
+		if err := i.store.Release(i.c, i.key); err != nil {
+			ctx.Error(err)
+		}
+		return
+	}
+	err := i.store.Complete(i.c, i.key, IdempotencyRecord{
+		Fingerprint: i.fingerprint,
+		Completed:   true,
+		Status:      status,
+		Header:      i.recorder.Header().Clone(),
+		Body:        i.recorder.body,
+	})
+	if err != nil {
+		ctx.Error(err)
+	}
+}
+
+// responseRecorder keeps a copy of the body written to a response
+type responseRecorder struct {
+	gin.ResponseWriter
+	body []byte
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body = append(r.body, data...)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body = append(r.body, s...)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore, for tests and
+// single instance deployments. Expired keys are evicted by Begin, at most
+// once per TTL.
+type MemoryIdempotencyStore struct {
+	// TTL is how long keys are kept, forever when zero
+	TTL     time.Duration
+	mtx     sync.Mutex
+	records map[string]memoryIdempotencyRecord
+	swept   time.Time
+}
+
+type memoryIdempotencyRecord struct {
+	IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore creates an in-memory store keeping keys for ttl
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{TTL: ttl}
+}
+
+func (s *MemoryIdempotencyStore) Begin(
+	ctx context.Context,
+	key string,
+	fingerprint string,
+) (*IdempotencyRecord, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.records == nil {
+		s.records = map[string]memoryIdempotencyRecord{}
+	}
+	now := time.Now()
+	s.sweep(now)
+	if rec, ok := s.records[key]; ok &&
+		(rec.expires.IsZero() || now.Before(rec.expires)) {
+		existing := rec.IdempotencyRecord
+		return &existing, nil
+	}
+	s.records[key] = memoryIdempotencyRecord{
+		IdempotencyRecord: IdempotencyRecord{Fingerprint: fingerprint},
+		expires:           s.expiry(now),
+	}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(
+	ctx context.Context,
+	key string,
+	record IdempotencyRecord,
+) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.records[key] = memoryIdempotencyRecord{
+		IdempotencyRecord: record,
+		expires:           s.expiry(time.Now()),
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(
+	ctx context.Context,
+	key string,
+) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// sweep evicts the expired keys, once per TTL
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if s.TTL <= 0 || now.Sub(s.swept) < s.TTL {
+		return
+	}
+	s.swept = now
+	for key, rec := range s.records {
+		if !rec.expires.IsZero() && !now.Before(rec.expires) {
+			delete(s.records, key)
+		}
+	}
+}
+
+func (s *MemoryIdempotencyStore) expiry(now time.Time) time.Time {
+	if s.TTL <= 0 {
+		return time.Time{}
+	}
+	return now.Add(s.TTL)
+}
@@ -0,0 +1,224 @@
+package runtime
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// idempotentRequest runs BeginIdempotent and handle for a request with the
+// key and headers, returning the response and whether it was replayed
+func idempotentRequest(
+	t *testing.T,
+	c context.Context,
+	store IdempotencyStore,
+	req proto.Message,
+	header map[string]string,
+	handle func(ctx *gin.Context),
+) (*httptest.ResponseRecorder, bool, error) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	ctx.Request.Header.Set(IdempotencyKeyHeader, "key")
+	for name, value := range header {
+		ctx.Request.Header.Set(name, value)
+	}
+	idem, err := BeginIdempotent(c, ctx, store, "Svc.Rpc", req)
+	if err != nil {
+		return w, false, err
+	}
+	if idem.Replayed() {
+		return w, true, nil
+	}
+	func() {
+		defer func() {
+			recover()
+		}()
+		defer idem.End(ctx)
+		handle(ctx)
+	}()
+	return w, false, nil
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ok := func(ctx *gin.Context) {
+		ctx.String(http.StatusCreated, "created")
+	}
+	compressed := func(ctx *gin.Context) {
+		ctx.Header("Content-Encoding", "gzip")
+		ctx.Data(http.StatusOK, "application/json", gzipped(t, `{"ok":true}`))
+	}
+	panics := func(ctx *gin.Context) {
+		panic("boom")
+	}
+	alice := WithPrincipal(context.Background(), "alice")
+	bob := WithPrincipal(context.Background(), "bob")
+
+	type request struct {
+		c      context.Context
+		req    proto.Message
+		header map[string]string
+		handle func(ctx *gin.Context)
+	}
+	tests := []struct {
+		name     string
+		first    request
+		second   request
+		err      error
+		replayed bool
+		body     string
+		encoding string
+	}{
+		{
+			name:     "replays completed key",
+			first:    request{handle: ok},
+			second:   request{handle: ok},
+			replayed: true,
+			body:     "created",
+		},
+		{
+			name:   "rejects key reused for another payload",
+			first:  request{req: wrapperspb.String("a"), handle: ok},
+			second: request{req: wrapperspb.String("b"), handle: ok},
+			err:    ErrIdempotencyKeyReused,
+		},
+		{
+			name: "rejects key reused for another representation",
+			first: request{
+				header: map[string]string{"Accept": "application/json"},
+				handle: ok,
+			},
+			second: request{
+				header: map[string]string{"Accept": "application/x-protobuf"},
+				handle: ok,
+			},
+			err: ErrIdempotencyKeyReused,
+		},
+		{
+			name: "decodes replays of unaccepted content codings",
+			first: request{
+				header: map[string]string{"Accept-Encoding": "gzip"},
+				handle: compressed,
+			},
+			second: request{
+				header: map[string]string{"Accept-Encoding": "identity"},
+				handle: ok,
+			},
+			replayed: true,
+			body:     `{"ok":true}`,
+		},
+		{
+			name: "keeps replays of accepted content codings",
+			first: request{
+				header: map[string]string{"Accept-Encoding": "gzip"},
+				handle: compressed,
+			},
+			second: request{
+				header: map[string]string{"Accept-Encoding": "gzip, br"},
+				handle: ok,
+			},
+			replayed: true,
+			body:     string(gzipped(t, `{"ok":true}`)),
+			encoding: "gzip",
+		},
+		{
+			name:   "releases key of panicking request",
+			first:  request{handle: panics},
+			second: request{handle: ok},
+			body:   "created",
+		},
+		{
+			name: "releases key of failed request",
+			first: request{handle: func(ctx *gin.Context) {
+				ctx.Status(http.StatusServiceUnavailable)
+			}},
+			second: request{handle: ok},
+			body:   "created",
+		},
+		{
+			name:   "scopes keys to principals",
+			first:  request{c: alice, handle: ok},
+			second: request{c: bob, handle: ok},
+			body:   "created",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryIdempotencyStore(time.Hour)
+			for i, r := range []request{tt.first, tt.second} {
+				if r.c == nil {
+					r.c = context.Background()
+				}
+				if r.req == nil {
+					r.req = wrapperspb.String("a")
+				}
+				w, replayed, err := idempotentRequest(t, r.c, store, r.req, r.header, r.handle)
+				if i == 0 {
+					if err != nil {
+						t.Fatalf("first request: %v", err)
+					}
+					continue
+				}
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				if tt.err != nil {
+					return
+				}
+				if replayed != tt.replayed {
+					t.Errorf("replayed = %v, want %v", replayed, tt.replayed)
+				}
+				body, _ := io.ReadAll(w.Body)
+				if string(body) != tt.body {
+					t.Errorf("body = %q, want %q", body, tt.body)
+				}
+				if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+					t.Errorf("Content-Encoding = %q, want %q", got, tt.encoding)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryIdempotencyStoreEviction(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Millisecond)
+	c := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := store.Begin(c, key, "fingerprint"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := store.Begin(c, "d", "fingerprint"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(store.records); n != 1 {
+		t.Errorf("%d keys kept, want 1", n)
+	}
+}
@@ -0,0 +1,298 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v3.19.6
+// source: runtime/internal/redacttest/redacttest.proto
+
+// Messages with sensitive fields, for the tests of the redaction of the
+// runtime
+
+package redacttest
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	_ "techunicorn.com/protoc-gen-gocqrshttp/custom/annotations"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Account struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string           `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Password string           `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	Pin      *string          `protobuf:"bytes,3,opt,name=pin,proto3,oneof" json:"pin,omitempty"`
+	Card     *Card            `protobuf:"bytes,4,opt,name=card,proto3" json:"card,omitempty"`
+	Cards    []*Card          `protobuf:"bytes,5,rep,name=cards,proto3" json:"cards,omitempty"`
+	Saved    map[string]*Card `protobuf:"bytes,6,rep,name=saved,proto3" json:"saved,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *Account) Reset() {
+	*x = Account{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_runtime_internal_redacttest_redacttest_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Account) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Account) ProtoMessage() {}
+
+func (x *Account) ProtoReflect() protoreflect.Message {
+	mi := &file_runtime_internal_redacttest_redacttest_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Account.ProtoReflect.Descriptor instead.
+func (*Account) Descriptor() ([]byte, []int) {
+	return file_runtime_internal_redacttest_redacttest_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Account) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Account) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *Account) GetPin() string {
+	if x != nil && x.Pin != nil {
+		return *x.Pin
+	}
+	return ""
+}
+
+func (x *Account) GetCard() *Card {
+	if x != nil {
+		return x.Card
+	}
+	return nil
+}
+
+func (x *Account) GetCards() []*Card {
+	if x != nil {
+		return x.Cards
+	}
+	return nil
+}
+
+func (x *Account) GetSaved() map[string]*Card {
+	if x != nil {
+		return x.Saved
+	}
+	return nil
+}
+
+type Card struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Number string `protobuf:"bytes,1,opt,name=number,proto3" json:"number,omitempty"`
+	Token  []byte `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	Holder string `protobuf:"bytes,3,opt,name=holder,proto3" json:"holder,omitempty"`
+}
+
+func (x *Card) Reset() {
+	*x = Card{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_runtime_internal_redacttest_redacttest_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Card) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Card) ProtoMessage() {}
+
+func (x *Card) ProtoReflect() protoreflect.Message {
+	mi := &file_runtime_internal_redacttest_redacttest_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Card.ProtoReflect.Descriptor instead.
+func (*Card) Descriptor() ([]byte, []int) {
+	return file_runtime_internal_redacttest_redacttest_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Card) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *Card) GetToken() []byte {
+	if x != nil {
+		return x.Token
+	}
+	return nil
+}
+
+func (x *Card) GetHolder() string {
+	if x != nil {
+		return x.Holder
+	}
+	return ""
+}
+
+var File_runtime_internal_redacttest_redacttest_proto protoreflect.FileDescriptor
+
+var file_runtime_internal_redacttest_redacttest_proto_rawDesc = []byte{
+	0x0a, 0x2c, 0x72, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e,
+	0x61, 0x6c, 0x2f, 0x72, 0x65, 0x64, 0x61, 0x63, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2f, 0x72, 0x65,
+	0x64, 0x61, 0x63, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0a,
+	0x72, 0x65, 0x64, 0x61, 0x63, 0x74, 0x74, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x61, 0x6e, 0x6e, 0x6f,
+	0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xbc, 0x02,
+	0x0a, 0x07, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x24, 0x0a,
+	0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x42,
+	0x08, 0xaa, 0xd3, 0xe4, 0x93, 0x02, 0x02, 0x18, 0x01, 0x52, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77,
+	0x6f, 0x72, 0x64, 0x12, 0x1f, 0x0a, 0x03, 0x70, 0x69, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x42, 0x08, 0xaa, 0xd3, 0xe4, 0x93, 0x02, 0x02, 0x18, 0x01, 0x48, 0x00, 0x52, 0x03, 0x70, 0x69,
+	0x6e, 0x88, 0x01, 0x01, 0x12, 0x24, 0x0a, 0x04, 0x63, 0x61, 0x72, 0x64, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x10, 0x2e, 0x72, 0x65, 0x64, 0x61, 0x63, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e,
+	0x43, 0x61, 0x72, 0x64, 0x52, 0x04, 0x63, 0x61, 0x72, 0x64, 0x12, 0x26, 0x0a, 0x05, 0x63, 0x61,
+	0x72, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x72, 0x65, 0x64, 0x61,
+	0x63, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x61, 0x72, 0x64, 0x52, 0x05, 0x63, 0x61, 0x72,
+	0x64, 0x73, 0x12, 0x34, 0x0a, 0x05, 0x73, 0x61, 0x76, 0x65, 0x64, 0x18, 0x06, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1e, 0x2e, 0x72, 0x65, 0x64, 0x61, 0x63, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x41,
+	0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x53, 0x61, 0x76, 0x65, 0x64, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x05, 0x73, 0x61, 0x76, 0x65, 0x64, 0x1a, 0x4a, 0x0a, 0x0a, 0x53, 0x61, 0x76, 0x65,
+	0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x72, 0x65, 0x64, 0x61, 0x63, 0x74,
+	0x74, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x61, 0x72, 0x64, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x42, 0x06, 0x0a, 0x04, 0x5f, 0x70, 0x69, 0x6e, 0x22, 0x60, 0x0a, 0x04,
+	0x43, 0x61, 0x72, 0x64, 0x12, 0x20, 0x0a, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x42, 0x08, 0xaa, 0xd3, 0xe4, 0x93, 0x02, 0x02, 0x18, 0x01, 0x52, 0x06,
+	0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x1e, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x42, 0x08, 0xaa, 0xd3, 0xe4, 0x93, 0x02, 0x02, 0x18, 0x01, 0x52,
+	0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x42, 0x43,
+	0x5a, 0x41, 0x74, 0x65, 0x63, 0x68, 0x75, 0x6e, 0x69, 0x63, 0x6f, 0x72, 0x6e, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x2d, 0x67, 0x65, 0x6e, 0x2d, 0x67, 0x6f, 0x63,
+	0x71, 0x72, 0x73, 0x68, 0x74, 0x74, 0x70, 0x2f, 0x72, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x2f,
+	0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x72, 0x65, 0x64, 0x61, 0x63, 0x74, 0x74,
+	0x65, 0x73, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_runtime_internal_redacttest_redacttest_proto_rawDescOnce sync.Once
+	file_runtime_internal_redacttest_redacttest_proto_rawDescData = file_runtime_internal_redacttest_redacttest_proto_rawDesc
+)
+
+func file_runtime_internal_redacttest_redacttest_proto_rawDescGZIP() []byte {
+	file_runtime_internal_redacttest_redacttest_proto_rawDescOnce.Do(func() {
+		file_runtime_internal_redacttest_redacttest_proto_rawDescData = protoimpl.X.CompressGZIP(file_runtime_internal_redacttest_redacttest_proto_rawDescData)
+	})
+	return file_runtime_internal_redacttest_redacttest_proto_rawDescData
+}
+
+var file_runtime_internal_redacttest_redacttest_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_runtime_internal_redacttest_redacttest_proto_goTypes = []interface{}{
+	(*Account)(nil), // 0: redacttest.Account
+	(*Card)(nil),    // 1: redacttest.Card
+	nil,             // 2: redacttest.Account.SavedEntry
+}
+var file_runtime_internal_redacttest_redacttest_proto_depIdxs = []int32{
+	1, // 0: redacttest.Account.card:type_name -> redacttest.Card
+	1, // 1: redacttest.Account.cards:type_name -> redacttest.Card
+	2, // 2: redacttest.Account.saved:type_name -> redacttest.Account.SavedEntry
+	1, // 3: redacttest.Account.SavedEntry.value:type_name -> redacttest.Card
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_runtime_internal_redacttest_redacttest_proto_init() }
+func file_runtime_internal_redacttest_redacttest_proto_init() {
+	if File_runtime_internal_redacttest_redacttest_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_runtime_internal_redacttest_redacttest_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Account); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_runtime_internal_redacttest_redacttest_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Card); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_runtime_internal_redacttest_redacttest_proto_msgTypes[0].OneofWrappers = []interface{}{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_runtime_internal_redacttest_redacttest_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_runtime_internal_redacttest_redacttest_proto_goTypes,
+		DependencyIndexes: file_runtime_internal_redacttest_redacttest_proto_depIdxs,
+		MessageInfos:      file_runtime_internal_redacttest_redacttest_proto_msgTypes,
+	}.Build()
+	File_runtime_internal_redacttest_redacttest_proto = out.File
+	file_runtime_internal_redacttest_redacttest_proto_rawDesc = nil
+	file_runtime_internal_redacttest_redacttest_proto_goTypes = nil
+	file_runtime_internal_redacttest_redacttest_proto_depIdxs = nil
+}
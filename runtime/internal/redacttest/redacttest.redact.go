@@ -0,0 +1,39 @@
+// Code generated by protoc-gen-gohttp. DO NOT EDIT.
+// source: runtime/internal/redacttest/redacttest.proto
+
+package redacttest
+
+import (
+	runtime "techunicorn.com/protoc-gen-gocqrshttp/runtime"
+)
+
+// Redact masks the sensitive fields of x, and of the messages it holds
+func (x *Account) Redact() {
+	if x == nil {
+		return
+	}
+	if x.Password != "" {
+		x.Password = runtime.RedactedString
+	}
+	if x.Pin != nil {
+		*x.Pin = runtime.RedactedString
+	}
+	x.Card.Redact()
+	for _, v := range x.Cards {
+		v.Redact()
+	}
+	for _, v := range x.Saved {
+		v.Redact()
+	}
+}
+
+// Redact masks the sensitive fields of x, and of the messages it holds
+func (x *Card) Redact() {
+	if x == nil {
+		return
+	}
+	if x.Number != "" {
+		x.Number = runtime.RedactedString
+	}
+	x.Token = nil
+}
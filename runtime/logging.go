@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+)
+
+// Keys of the attributes of the logs of this package
+const (
+	MethodLogKey    = "method"
+	KindLogKey      = "kind"
+	RouteLogKey     = "route"
+	StatusLogKey    = "status"
+	DurationLogKey  = "duration"
+	PrincipalLogKey = "principal"
+	PayloadLogKey   = "payload"
+	ErrorLogKey     = "error"
+	OperationLogKey = "operation"
+)
+
+// AuditLog is a Behavior logging every dispatch with its principal and its
+// payload, sensitive fields masked, at the info level or the error level when
+// it fails
+func AuditLog(logger *slog.Logger) Behavior {
+	return func(
+		c context.Context,
+		method string,
+		req proto.Message,
+		next Handler,
+	) (proto.Message, error) {
+		start := time.Now()
+		res, err := next(c, req)
+
+		attrs := []slog.Attr{
+			slog.String(MethodLogKey, method),
+			slog.Duration(DurationLogKey, time.Since(start)),
+		}
+		if principal, ok := PrincipalFrom(c); ok {
+			attrs = append(attrs, slog.Any(PrincipalLogKey, principal))
+		}
+		attrs = append(attrs, slog.Any(PayloadLogKey, Redacted{Message: req}))
+		level := slog.LevelInfo
+		if err != nil {
+			level = slog.LevelError
+			attrs = append(attrs, slog.String(ErrorLogKey, err.Error()))
+			if status := StatusOf(err); status != 0 {
+				attrs = append(attrs, slog.Int(StatusLogKey, status))
+			}
+		}
+		logger.LogAttrs(c, level, "dispatched", attrs...)
+		return res, err
+	}
+}
+
+// accessLog is an Observer logging every request
+type accessLog struct {
+	logger *slog.Logger
+}
+
+// AccessLog is an Observer logging every request with its rpc, status and
+// duration, at the info level or the error level for statuses of 500 and
+// above
+func AccessLog(logger *slog.Logger) Observer {
+	return accessLog{logger: logger}
+}
+
+func (l accessLog) Observe(ctx *gin.Context, rpc RPCInfo) func() {
+	start := time.Now()
+	return func() {
+		status := ResponseStatus(ctx)
+		attrs := []slog.Attr{
+			slog.String(MethodLogKey, rpc.Method),
+			slog.String(KindLogKey, rpc.Kind),
+			slog.String(RouteLogKey, rpc.Route),
+			slog.Int(StatusLogKey, status),
+			slog.Duration(DurationLogKey, time.Since(start)),
+		}
+		if principal, ok := ctx.Get(principalKey); ok {
+			attrs = append(attrs, slog.Any(PrincipalLogKey, principal))
+		}
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		if last := ctx.Errors.Last(); last != nil {
+			attrs = append(attrs, slog.String(ErrorLogKey, last.Error()))
+		}
+		l.logger.LogAttrs(ctx.Request.Context(), level, "request", attrs...)
+	}
+}
@@ -0,0 +1,174 @@
+// Package metrics measures the requests of the generated handlers with
+// Prometheus
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"techunicorn.com/protoc-gen-gocqrshttp/runtime"
+)
+
+// Namespace prefixes the names of the metrics
+const Namespace = "cqrshttp"
+
+// Labels of the metrics, the status label only applying to errors
+const (
+	ServiceLabel = "service"
+	MethodLabel  = "method"
+	KindLabel    = "kind"
+	StatusLabel  = "status"
+)
+
+var rpcLabels = []string{ServiceLabel, MethodLabel, KindLabel}
+
+// Observer is a runtime.Observer counting the requests and errors of a fixed
+// set of rpcs and recording their duration and sizes. Requests to other rpcs
+// are not measured, which bounds the cardinality of the metrics.
+type Observer struct {
+	rpcs         map[string]bool
+	requests     *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+var _ runtime.Observer = (*Observer)(nil)
+
+// New registers the metrics of rpcs, such as the generated <Service>RPCs,
+// with registerer. Metrics already registered, by the observer of another
+// service, are shared.
+func New(registerer prometheus.Registerer, rpcs []runtime.RPCInfo) (*Observer, error) {
+	o := &Observer{rpcs: make(map[string]bool, len(rpcs))}
+	var err error
+	if o.requests, err = register(registerer, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "requests_total",
+			Help:      "Number of requests to the rpcs",
+		},
+		rpcLabels,
+	)); err != nil {
+		return nil, err
+	}
+	if o.errors, err = register(registerer, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "errors_total",
+			Help:      "Number of requests to the rpcs failing, by status",
+		},
+		append(rpcLabels, StatusLabel),
+	)); err != nil {
+		return nil, err
+	}
+	if o.duration, err = register(registerer, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the requests to the rpcs",
+			Buckets:   prometheus.DefBuckets,
+		},
+		rpcLabels,
+	)); err != nil {
+		return nil, err
+	}
+	if o.requestSize, err = register(registerer, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "request_size_bytes",
+			Help:      "Size of the request bodies of the rpcs",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		},
+		rpcLabels,
+	)); err != nil {
+		return nil, err
+	}
+	if o.responseSize, err = register(registerer, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "response_size_bytes",
+			Help:      "Size of the response bodies of the rpcs",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		},
+		rpcLabels,
+	)); err != nil {
+		return nil, err
+	}
+
+	// the series of every rpc are initialized so that they are exported
+	// before the first request
+	for _, rpc := range rpcs {
+		o.rpcs[rpc.Method] = true
+		labels := rpcValues(rpc)
+		o.requests.WithLabelValues(labels...)
+		o.duration.WithLabelValues(labels...)
+		o.requestSize.WithLabelValues(labels...)
+		o.responseSize.WithLabelValues(labels...)
+	}
+	return o, nil
+}
+
+// MustNew is New panicking when the metrics cannot be registered
+func MustNew(registerer prometheus.Registerer, rpcs []runtime.RPCInfo) *Observer {
+	o, err := New(registerer, rpcs)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// Registerer returns the Metrics option of the generated <Service>HTTPOptions
+// registering the metrics of the rpcs of a service with registerer, panicking
+// when they conflict with metrics registered otherwise
+func Registerer(registerer prometheus.Registerer) func([]runtime.RPCInfo) runtime.Observer {
+	return func(rpcs []runtime.RPCInfo) runtime.Observer {
+		return MustNew(registerer, rpcs)
+	}
+}
+
+// register registers c, returning the collector already registered in its
+// place if any
+func register[T prometheus.Collector](registerer prometheus.Registerer, c T) (T, error) {
+	err := registerer.Register(c)
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		existing, ok := are.ExistingCollector.(T)
+		if !ok {
+			return c, err
+		}
+		return existing, nil
+	}
+	return c, err
+}
+
+// rpcValues returns the values of the labels of rpc
+func rpcValues(rpc runtime.RPCInfo) []string {
+	return []string{rpc.Service, rpc.Method, rpc.Kind}
+}
+
+// Observe measures a request when its rpc is one of those of the observer
+func (o *Observer) Observe(ctx *gin.Context, rpc runtime.RPCInfo) func() {
+	if !o.rpcs[rpc.Method] {
+		return func() {}
+	}
+	start := time.Now()
+
+	return func() {
+		labels := rpcValues(rpc)
+		o.requests.WithLabelValues(labels...).Inc()
+		o.duration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		if size := ctx.Request.ContentLength; size >= 0 {
+			o.requestSize.WithLabelValues(labels...).Observe(float64(size))
+		}
+		if size := ctx.Writer.Size(); size >= 0 {
+			o.responseSize.WithLabelValues(labels...).Observe(float64(size))
+		}
+		if status := runtime.ResponseStatus(ctx); status >= 400 {
+			o.errors.WithLabelValues(append(labels, strconv.Itoa(status))...).Inc()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"techunicorn.com/protoc-gen-gocqrshttp/runtime"
+)
+
+func TestObserver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rpc := runtime.RPCInfo{Service: "orders.Orders", Method: "orders.Orders.PlaceOrder", Kind: "command"}
+	other := runtime.RPCInfo{Service: "orders.Orders", Method: "orders.Orders.Other", Kind: "query"}
+	tests := []struct {
+		name     string
+		rpc      runtime.RPCInfo
+		handle   func(ctx *gin.Context)
+		requests float64
+		errors   map[string]float64
+	}{
+		{
+			name: "succeeds",
+			rpc:  rpc,
+			handle: func(ctx *gin.Context) {
+				ctx.String(http.StatusCreated, "created")
+			},
+			requests: 1,
+		},
+		{
+			name: "fails with a client error",
+			rpc:  rpc,
+			handle: func(ctx *gin.Context) {
+				runtime.Error(ctx, runtime.NewError(http.StatusConflict, errors.New("conflict")))
+			},
+			requests: 1,
+			errors:   map[string]float64{"409": 1},
+		},
+		{
+			name: "fails with an error left to the middlewares",
+			rpc:  rpc,
+			handle: func(ctx *gin.Context) {
+				runtime.Error(ctx, errors.New("database is down"))
+			},
+			requests: 1,
+			errors:   map[string]float64{"500": 1},
+		},
+		{
+			name: "ignores other rpcs",
+			rpc:  other,
+			handle: func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "ok")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			o, err := New(registry, []runtime.RPCInfo{rpc})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+			end := o.Observe(ctx, tt.rpc)
+			tt.handle(ctx)
+			end()
+
+			labels := rpcValues(rpc)
+			if got := testutil.ToFloat64(o.requests.WithLabelValues(labels...)); got != tt.requests {
+				t.Errorf("%v requests, want %v", got, tt.requests)
+			}
+			if n := testutil.CollectAndCount(o.errors); n != len(tt.errors) {
+				t.Errorf("%d error series, want %d", n, len(tt.errors))
+			}
+			for status, want := range tt.errors {
+				errs := o.errors.WithLabelValues(append(labels, status)...)
+				if got := testutil.ToFloat64(errs); got != want {
+					t.Errorf("%v errors of status %s, want %v", got, status, want)
+				}
+			}
+			// the series of the rpc are exported before its first request
+			if n := testutil.CollectAndCount(o.requests); n != 1 {
+				t.Errorf("%d request series, want 1", n)
+			}
+		})
+	}
+}
+
+func TestNewSharesMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	a, err := New(registry, []runtime.RPCInfo{{Method: "a.A.Rpc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(registry, []runtime.RPCInfo{{Method: "b.B.Rpc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.requests != b.requests {
+		t.Error("observers of two services do not share their metrics")
+	}
+	if n := testutil.CollectAndCount(a.requests); n != 2 {
+		t.Errorf("%d request series, want 2", n)
+	}
+}
+
+func TestRegisterer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	rpcs := []runtime.RPCInfo{{Method: "a.A.Rpc"}}
+	o, ok := Registerer(registry)(rpcs).(*Observer)
+	if !ok || !o.rpcs["a.A.Rpc"] {
+		t.Fatalf("observer = %v", o)
+	}
+	conflicting := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "errors_total",
+	})
+	other := prometheus.NewRegistry()
+	other.MustRegister(conflicting)
+	defer func() {
+		if recover() == nil {
+			t.Error("conflicting metrics registered")
+		}
+	}()
+	Registerer(other)(rpcs)
+}
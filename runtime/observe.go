@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RPCInfo describes an rpc served by the generated handlers
+type RPCInfo struct {
+	// Service is the full name of the service of the rpc
+	Service string
+	// Method is the full name of the rpc
+	Method string
+	// Kind is command, query or other
+	Kind string
+	// Route is the route of the rpc within its router group
+	Route string
+}
+
+// Observer observes the requests of the generated handlers, for tracing
+// and metrics
+type Observer interface {
+	// Observe is called before a request to rpc is handled, returning a
+	// function called once it was
+	Observe(ctx *gin.Context, rpc RPCInfo) func()
+}
+
+// Observe starts observing a request with each of observers, returning a
+// function ending the observations in reverse order
+func Observe(ctx *gin.Context, observers []Observer, rpc RPCInfo) func() {
+	if len(observers) == 0 {
+		return func() {}
+	}
+	ends := make([]func(), len(observers))
+	for i, observer := range observers {
+		ends[i] = observer.Observe(ctx, rpc)
+	}
+	return func() {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i]()
+		}
+	}
+}
+
+// ResponseStatus returns the status of the response of a request, assuming
+// 500 for requests aborted with an error whose rendering is left to the
+// error handling middlewares
+func ResponseStatus(ctx *gin.Context) int {
+	if !ctx.Writer.Written() && len(ctx.Errors) != 0 &&
+		ctx.Writer.Status() == http.StatusOK {
+		return http.StatusInternalServerError
+	}
+	return ctx.Writer.Status()
+}
+
+// WrapContext applies wrap to the context of a request, and to the one set
+// with SetContext if any, so that the values it adds reach the application
+// whichever the ContextFactory uses
+func WrapContext(ctx *gin.Context, wrap func(context.Context) context.Context) {
+	ctx.Request = ctx.Request.WithContext(wrap(ctx.Request.Context()))
+	if v, ok := ctx.Get(contextKey); ok {
+		ctx.Set(contextKey, wrap(v.(context.Context)))
+	}
+	if v, ok := ctx.Get(LegacyContextKey); ok {
+		if legacy, ok := v.(context.Context); ok {
+			ctx.Set(LegacyContextKey, wrap(legacy))
+		}
+	}
+}
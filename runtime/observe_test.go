@@ -0,0 +1,93 @@
+package runtime
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordingObserver records the calls of the observers sharing calls
+type recordingObserver struct {
+	name  string
+	calls *[]string
+}
+
+func (o recordingObserver) Observe(ctx *gin.Context, rpc RPCInfo) func() {
+	*o.calls = append(*o.calls, "start "+o.name+" "+rpc.Method)
+	return func() {
+		*o.calls = append(*o.calls, "end "+o.name)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	calls := []string{}
+	observers := []Observer{
+		recordingObserver{name: "tracing", calls: &calls},
+		recordingObserver{name: "metrics", calls: &calls},
+	}
+	end := Observe(ctx, observers, RPCInfo{Method: "Svc.Rpc"})
+	end()
+	want := []string{
+		"start tracing Svc.Rpc",
+		"start metrics Svc.Rpc",
+		"end metrics",
+		"end tracing",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	Observe(ctx, nil, RPCInfo{})()
+}
+
+func TestResponseStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		handle func(ctx *gin.Context)
+		want   int
+	}{
+		{
+			name: "written",
+			handle: func(ctx *gin.Context) {
+				ctx.Status(http.StatusCreated)
+				ctx.Writer.WriteHeaderNow()
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name: "rendered error",
+			handle: func(ctx *gin.Context) {
+				Error(ctx, NewError(http.StatusConflict, errors.New("conflict")))
+			},
+			want: http.StatusConflict,
+		},
+		{
+			name: "error left to the middlewares",
+			handle: func(ctx *gin.Context) {
+				Error(ctx, errors.New("database is down"))
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name:   "nothing written",
+			handle: func(ctx *gin.Context) {},
+			want:   http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+			tt.handle(ctx)
+			if got := ResponseStatus(ctx); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// OperationState is the progress of an asynchronous command
+type OperationState string
+
+// States of an operation
+const (
+	OperationPending   OperationState = "pending"
+	OperationSucceeded OperationState = "succeeded"
+	OperationFailed    OperationState = "failed"
+)
+
+var (
+	// ErrOperationNotFound is returned when polling an unknown operation
+	ErrOperationNotFound = NewError(
+		http.StatusNotFound,
+		errors.New("operation not found"),
+	)
+	// ErrNoOperationStore is returned when an rpc is asynchronous but no
+	// store was registered
+	ErrNoOperationStore = NewError(
+		http.StatusInternalServerError,
+		errors.New("no operation store registered"),
+	)
+)
+
+// Operation is the status of an asynchronous command
+type Operation struct {
+	ID string
+	// Method is the full name of the rpc of the command
+	Method string
+	// Principal is the caller who started the operation, formatted with
+	// fmt.Sprint, the only one it is served to. It is empty for anonymous
+	// callers.
+	Principal string
+	State     OperationState
+	// Result is the output of the command once it succeeded
+	Result proto.Message
+	// Error is what the command failed with
+	Error   *OperationError
+	Created time.Time
+	Updated time.Time
+}
+
+// OperationError is the error of a failed operation
+type OperationError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// OperationStore stores the status of asynchronous commands
+type OperationStore interface {
+	// Create stores a new operation
+	Create(ctx context.Context, op *Operation) error
+	// Update replaces the status of an operation
+	Update(ctx context.Context, op *Operation) error
+	// Get returns an operation, failing with ErrOperationNotFound when it
+	// is unknown
+	Get(ctx context.Context, id string) (*Operation, error)
+}
+
+// StartOperation runs the command of the rpc method in the background,
+// returning its pending operation started by the principal of c. The command
+// gets the values of c without its cancellation, as it outlives the request.
+// Errors of unknown status and panics are stored as a generic 500, their
+// cause being logged with the default logger.
+func StartOperation(
+	c context.Context,
+	store OperationStore,
+	method string,
+	run func(context.Context) (proto.Message, error),
+) (*Operation, error) {
+	if store == nil {
+		return nil, ErrNoOperationStore
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	op := &Operation{
+		ID:        hex.EncodeToString(id),
+		Method:    method,
+		Principal: operationPrincipal(c),
+		State:     OperationPending,
+		Created:   now,
+		Updated:   now,
+	}
+	if err := store.Create(c, op); err != nil {
+		return nil, err
+	}
+
+	bg := detachedContext{c}
+	pending := *op
+	go func() {
+		done := pending
+		defer func() {
+			if r := recover(); r != nil {
+				done.State, done.Result = OperationFailed, nil
+				done.Error = operationError(bg, &done, fmt.Errorf("%v", r))
+			}
+			done.Updated = time.Now()
+			updateOperation(bg, store, &done)
+		}()
+		res, err := run(bg)
+		if err != nil {
+			done.State = OperationFailed
+			done.Error = operationError(bg, &done, err)
+			return
+		}
+		done.State, done.Result = OperationSucceeded, res
+	}()
+	return op, nil
+}
+
+// operationPrincipal is the principal of c as stored in operations
+func operationPrincipal(c context.Context) string {
+	if principal, ok := PrincipalFrom(c); ok {
+		return fmt.Sprint(principal)
+	}
+	return ""
+}
+
+// operationError is the error stored for an operation failing with err,
+// whose message is hidden and logged with the default logger when its
+// status is unknown or 500 and above, as anyone polling the operation gets
+// it
+func operationError(c context.Context, op *Operation, err error) *OperationError {
+	status := StatusOf(err)
+	if status != 0 && status < http.StatusInternalServerError {
+		return &OperationError{Status: status, Message: err.Error()}
+	}
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	slog.Default().LogAttrs(
+		c,
+		slog.LevelError,
+		"operation failed",
+		slog.String(OperationLogKey, op.ID),
+		slog.String(MethodLogKey, op.Method),
+		slog.String(ErrorLogKey, err.Error()),
+	)
+	return &OperationError{Status: status, Message: http.StatusText(status)}
+}
+
+// operationUpdateAttempts is how many times the status of a done operation
+// is stored before giving up
+const operationUpdateAttempts = 3
+
+// updateOperation stores the status of a done operation, retrying failures
+// and logging the last one with the default logger, as no request is left to
+// fail
+func updateOperation(c context.Context, store OperationStore, op *Operation) {
+	var err error
+	for attempt := 0; attempt < operationUpdateAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+		if err = store.Update(c, op); err == nil {
+			return
+		}
+	}
+	slog.Default().LogAttrs(
+		c,
+		slog.LevelError,
+		"operation status not stored",
+		slog.String(OperationLogKey, op.ID),
+		slog.String(MethodLogKey, op.Method),
+		slog.String(ErrorLogKey, err.Error()),
+	)
+}
+
+// detachedContext keeps the values of a context without its deadline and
+// cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// resultCodec renders the messages embedded in json documents
+var resultCodec = JSONCodec{
+	MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true},
+}
+
+// operationStatus is the json representation of an operation
+type operationStatus struct {
+	ID      string          `json:"id"`
+	Method  string          `json:"method"`
+	State   OperationState  `json:"state"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *OperationError `json:"error,omitempty"`
+	Created time.Time       `json:"created"`
+	Updated time.Time       `json:"updated"`
+}
+
+func writeOperation(ctx *gin.Context, status int, op *Operation) error {
+	body := operationStatus{
+		ID:      op.ID,
+		Method:  op.Method,
+		State:   op.State,
+		Error:   op.Error,
+		Created: op.Created,
+		Updated: op.Updated,
+	}
+	if op.Result != nil {
+		raw, err := resultCodec.Marshal(op.Result)
+		if err != nil {
+			return err
+		}
+		body.Result = raw
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx.Status(status)
+	ctx.Header("Content-Type", MediaTypeJSON)
+	_, err = ctx.Writer.Write(raw)
+	return err
+}
+
+// Accepted answers a request with 202 and its pending operation, located
+// under the route of the operations at base
+func Accepted(ctx *gin.Context, base string, op *Operation) error {
+	ctx.Header("Location", base+"/"+op.ID)
+	return writeOperation(ctx, http.StatusAccepted, op)
+}
+
+// OperationHandler serves the status of the operation with the id path
+// parameter, as json, to the principal who started it
+func OperationHandler(store OperationStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, err := Context(ctx, nil)
+		if err != nil {
+			Error(ctx, err)
+			return
+		}
+		ServeOperation(ctx, c, store, nil)
+	}
+}
+
+// ServeOperation answers a request with the status of the operation with the
+// id path parameter, as json. Operations started by another principal than
+// the one of c are not found, and authorize, when not nil, checks that the
+// caller meets the requirements of the rpc of the operation.
+func ServeOperation(
+	ctx *gin.Context,
+	c context.Context,
+	store OperationStore,
+	authorize func(c context.Context, method string) error,
+) {
+	if store == nil {
+		Error(ctx, ErrNoOperationStore)
+		return
+	}
+	op, err := store.Get(c, ctx.Param("id"))
+	if err != nil {
+		Error(ctx, err)
+		return
+	}
+	if op.Principal != operationPrincipal(c) {
+		Error(ctx, ErrOperationNotFound)
+		return
+	}
+	if authorize != nil {
+		if err := authorize(c, op.Method); err != nil {
+			Error(ctx, err)
+			return
+		}
+	}
+	if op.State == OperationPending {
+		ctx.Header("Retry-After", "1")
+	}
+	if err := writeOperation(ctx, http.StatusOK, op); err != nil {
+		Error(ctx, err)
+	}
+}
+
+// MemoryOperationStore is an in-memory OperationStore, for tests and single
+// instance deployments. Expired operations are evicted by Create, at most
+// once per TTL.
+type MemoryOperationStore struct {
+	// TTL is how long operations are kept once done, forever when zero
+	TTL        time.Duration
+	mtx        sync.Mutex
+	operations map[string]Operation
+	swept      time.Time
+}
+
+// NewMemoryOperationStore creates an in-memory store keeping operations for
+// ttl once done
+func NewMemoryOperationStore(ttl time.Duration) *MemoryOperationStore {
+	return &MemoryOperationStore{TTL: ttl}
+}
+
+func (s *MemoryOperationStore) Create(
+	ctx context.Context,
+	op *Operation,
+) error {
+	s.mtx.Lock()
+	s.sweep(time.Now())
+	s.mtx.Unlock()
+	return s.Update(ctx, op)
+}
+
+func (s *MemoryOperationStore) Update(
+	ctx context.Context,
+	op *Operation,
+) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.operations == nil {
+		s.operations = map[string]Operation{}
+	}
+	s.operations[op.ID] = *op
+	return nil
+}
+
+func (s *MemoryOperationStore) Get(
+	ctx context.Context,
+	id string,
+) (*Operation, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	op, ok := s.operations[id]
+	if !ok {
+		return nil, ErrOperationNotFound
+	}
+	if s.expired(op, time.Now()) {
+		delete(s.operations, id)
+		return nil, ErrOperationNotFound
+	}
+	return &op, nil
+}
+
+// sweep evicts the expired operations, once per TTL
+func (s *MemoryOperationStore) sweep(now time.Time) {
+	if s.TTL <= 0 || now.Sub(s.swept) < s.TTL {
+		return
+	}
+	s.swept = now
+	for id, op := range s.operations {
+		if s.expired(op, now) {
+			delete(s.operations, id)
+		}
+	}
+}
+
+// expired reports whether an operation is done since longer than the TTL
+func (s *MemoryOperationStore) expired(op Operation, now time.Time) bool {
+	return s.TTL > 0 && op.State != OperationPending && now.Sub(op.Updated) > s.TTL
+}
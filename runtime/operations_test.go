@@ -0,0 +1,203 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// flakyOperationStore fails the first updates of a MemoryOperationStore
+type flakyOperationStore struct {
+	*MemoryOperationStore
+	mtx      sync.Mutex
+	failures int
+	done     chan struct{}
+}
+
+func (s *flakyOperationStore) Update(ctx context.Context, op *Operation) error {
+	if op.State != OperationPending {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		if s.failures > 0 {
+			s.failures--
+			return errors.New("unavailable")
+		}
+		defer close(s.done)
+	}
+	return s.MemoryOperationStore.Update(ctx, op)
+}
+
+func TestStartOperation(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(context.Context) (proto.Message, error)
+		failures int
+		state    OperationState
+		status   int
+		message  string
+	}{
+		{
+			name: "succeeds",
+			run: func(context.Context) (proto.Message, error) {
+				return wrapperspb.String("done"), nil
+			},
+			state: OperationSucceeded,
+		},
+		{
+			name: "fails with the status of its error",
+			run: func(context.Context) (proto.Message, error) {
+				return nil, NewError(http.StatusConflict, errors.New("conflict"))
+			},
+			state:   OperationFailed,
+			status:  http.StatusConflict,
+			message: "conflict",
+		},
+		{
+			name: "hides errors of unknown status",
+			run: func(context.Context) (proto.Message, error) {
+				return nil, errors.New("database password rejected")
+			},
+			state:   OperationFailed,
+			status:  http.StatusInternalServerError,
+			message: http.StatusText(http.StatusInternalServerError),
+		},
+		{
+			name: "hides server errors",
+			run: func(context.Context) (proto.Message, error) {
+				return nil, NewError(http.StatusBadGateway, errors.New("upstream at 10.0.0.1 failed"))
+			},
+			state:   OperationFailed,
+			status:  http.StatusBadGateway,
+			message: http.StatusText(http.StatusBadGateway),
+		},
+		{
+			name: "fails when panicking",
+			run: func(context.Context) (proto.Message, error) {
+				panic("boom")
+			},
+			state:   OperationFailed,
+			status:  http.StatusInternalServerError,
+			message: http.StatusText(http.StatusInternalServerError),
+		},
+		{
+			name: "retries failed updates",
+			run: func(context.Context) (proto.Message, error) {
+				return wrapperspb.String("done"), nil
+			},
+			failures: operationUpdateAttempts - 1,
+			state:    OperationSucceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &flakyOperationStore{
+				MemoryOperationStore: NewMemoryOperationStore(0),
+				failures:             tt.failures,
+				done:                 make(chan struct{}),
+			}
+			c := WithPrincipal(context.Background(), "alice")
+			op, err := StartOperation(c, store, "Svc.Rpc", tt.run)
+			if err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-store.done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("operation status not stored")
+			}
+			got, err := store.Get(context.Background(), op.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.State != tt.state {
+				t.Errorf("state = %s, want %s", got.State, tt.state)
+			}
+			if tt.status != 0 && (got.Error == nil || got.Error.Status != tt.status || got.Error.Message != tt.message) {
+				t.Errorf("error = %+v, want status %d and message %q", got.Error, tt.status, tt.message)
+			}
+			if got.Principal != "alice" {
+				t.Errorf("principal = %q, want alice", got.Principal)
+			}
+		})
+	}
+}
+
+func TestMemoryOperationStoreEviction(t *testing.T) {
+	store := NewMemoryOperationStore(time.Millisecond)
+	c := context.Background()
+	past := time.Now().Add(-time.Hour)
+	for id, state := range map[string]OperationState{
+		"done":    OperationSucceeded,
+		"failed":  OperationFailed,
+		"pending": OperationPending,
+	} {
+		op := &Operation{ID: id, State: state, Updated: past}
+		if err := store.Create(c, op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := store.Create(c, &Operation{ID: "new", State: OperationPending}); err != nil {
+		t.Fatal(err)
+	}
+	for id, kept := range map[string]bool{
+		"done":    false,
+		"failed":  false,
+		"pending": true,
+		"new":     true,
+	} {
+		if _, ok := store.operations[id]; ok != kept {
+			t.Errorf("operation %s kept %v, want %v", id, ok, kept)
+		}
+	}
+}
+
+func TestServeOperation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryOperationStore(0)
+	op := &Operation{ID: "op", Method: "Svc.Rpc", Principal: "alice", State: OperationSucceeded}
+	if err := store.Create(context.Background(), op); err != nil {
+		t.Fatal(err)
+	}
+	denied := func(context.Context, string) error {
+		return NewError(http.StatusForbidden, ErrPermissionDenied)
+	}
+	tests := []struct {
+		name      string
+		id        string
+		principal interface{}
+		authorize func(context.Context, string) error
+		status    int
+	}{
+		{name: "principal", id: "op", principal: "alice", status: http.StatusOK},
+		{name: "other principal", id: "op", principal: "bob", status: http.StatusNotFound},
+		{name: "anonymous", id: "op", status: http.StatusNotFound},
+		{name: "unknown", id: "other", principal: "alice", status: http.StatusNotFound},
+		{name: "unauthorized", id: "op", principal: "alice", authorize: denied, status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			ctx.Params = gin.Params{{Key: "id", Value: tt.id}}
+			c := context.Background()
+			if tt.principal != nil {
+				c = WithPrincipal(c, tt.principal)
+			}
+			ServeOperation(ctx, c, store, tt.authorize)
+			ctx.Writer.WriteHeaderNow()
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Headers of the messages consumed from queues
+const (
+	// MessageTypeHeader carries the full name of the command of a message
+	MessageTypeHeader = "Type"
+	// MessageContentTypeHeader carries the media type of the payload of a
+	// message, protojson when empty
+	MessageContentTypeHeader = "Content-Type"
+)
+
+// Message is a message received from a queue
+type Message interface {
+	// Header returns the value of a header of the message, empty when it is
+	// not set
+	Header(key string) string
+	// Body returns the payload of the message
+	Body() []byte
+}
+
+// Acker settles a message received from a queue
+type Acker interface {
+	// Ack reports that the message was handled
+	Ack(ctx context.Context) error
+	// Nack reports that the message could not be handled, requeue telling
+	// whether it is worth retrying
+	Nack(ctx context.Context, requeue bool) error
+}
+
+// PublishFunc publishes an event to a queue, eventType being the full name
+// of its message
+type PublishFunc func(ctx context.Context, eventType string, event proto.Message) error
+
+// ConsumeFunc handles a message received from a queue
+type ConsumeFunc func(ctx context.Context, msg Message, acker Acker) error
+
+// Consume decodes a message into the command named by its type header with
+// newInput and hands it to dispatch. The message is acked once handled and
+// nacked when it fails, being requeued unless it is malformed or the
+// command failed with a 4xx status.
+func Consume(
+	ctx context.Context,
+	msg Message,
+	acker Acker,
+	newInput func(name string) (proto.Message, bool),
+	dispatch func(c context.Context, req proto.Message) (proto.Message, error),
+) error {
+	req, err := decodeMessage(msg, newInput)
+	if err != nil {
+		if nerr := acker.Nack(ctx, false); nerr != nil {
+			return nerr
+		}
+		return err
+	}
+	if _, err := dispatch(ctx, req); err != nil {
+		status := StatusOf(err)
+		requeue := status == 0 || status >= http.StatusInternalServerError
+		if nerr := acker.Nack(ctx, requeue); nerr != nil {
+			return nerr
+		}
+		return err
+	}
+	return acker.Ack(ctx)
+}
+
+func decodeMessage(
+	msg Message,
+	newInput func(name string) (proto.Message, bool),
+) (proto.Message, error) {
+	name := msg.Header(MessageTypeHeader)
+	req, ok := newInput(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCommand, name)
+	}
+	codec := Codecs[0]
+	if contentType := msg.Header(MessageContentTypeHeader); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, ErrUnsupportedMediaType
+		}
+		if codec = codecFor(mediaType); codec == nil {
+			return nil, ErrUnsupportedMediaType
+		}
+	}
+	if body := msg.Body(); len(body) != 0 {
+		if err := codec.Unmarshal(body, req); err != nil {
+			return nil, NewError(http.StatusBadRequest, err)
+		}
+	}
+	return req, nil
+}
+
+// DrainMaxDeliveries bounds the deliveries of a message by Drain when the
+// MaxDeliveries of its broker is zero
+const DrainMaxDeliveries = 10
+
+// MemoryBroker is an in-memory queue, for tests
+type MemoryBroker struct {
+	// MaxDeliveries is how many times a message is delivered before being
+	// dead lettered rather than requeued, unlimited when zero
+	MaxDeliveries int
+	queue         chan *MemoryMessage
+	// retried signals that a message was requeued
+	retried chan struct{}
+	mtx     sync.Mutex
+	retries []*MemoryMessage
+	dead    []*MemoryMessage
+}
+
+// NewMemoryBroker creates an in-memory queue holding up to size published
+// messages, requeued ones being held apart so that requeuing never blocks
+func NewMemoryBroker(size int) *MemoryBroker {
+	return &MemoryBroker{
+		queue:   make(chan *MemoryMessage, size),
+		retried: make(chan struct{}, 1),
+	}
+}
+
+// MemoryMessage is a message of a MemoryBroker
+type MemoryMessage struct {
+	Headers map[string]string
+	Payload []byte
+	// Deliveries counts how many times the message was consumed
+	Deliveries int
+	broker     *MemoryBroker
+}
+
+func (m *MemoryMessage) Header(key string) string {
+	return m.Headers[key]
+}
+
+func (m *MemoryMessage) Body() []byte {
+	return m.Payload
+}
+
+func (m *MemoryMessage) Ack(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryMessage) Nack(ctx context.Context, requeue bool) error {
+	b := m.broker
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if limit := b.MaxDeliveries; requeue && (limit <= 0 || m.Deliveries < limit) {
+		b.retries = append(b.retries, m)
+		select {
+		case b.retried <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	b.dead = append(b.dead, m)
+	return nil
+}
+
+// Publish queues a message of the given type and media type
+func (b *MemoryBroker) Publish(
+	ctx context.Context,
+	msgType string,
+	contentType string,
+	body []byte,
+) error {
+	msg := &MemoryMessage{
+		Headers: map[string]string{
+			MessageTypeHeader:        msgType,
+			MessageContentTypeHeader: contentType,
+		},
+		Payload: body,
+		broker:  b,
+	}
+	select {
+	case b.queue <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PublishMessage is a PublishFunc queuing msg as protojson
+func (b *MemoryBroker) PublishMessage(
+	ctx context.Context,
+	msgType string,
+	msg proto.Message,
+) error {
+	raw, err := Codecs[0].Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.Publish(ctx, msgType, Codecs[0].MediaType(), raw)
+}
+
+// next returns the next published message, or else the first requeued one
+// delivered fewer than limit times, unlimited when zero, nil when there is
+// none
+func (b *MemoryBroker) next(limit int) *MemoryMessage {
+	select {
+	case msg := <-b.queue:
+		return msg
+	default:
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for i, msg := range b.retries {
+		if limit <= 0 || msg.Deliveries < limit {
+			b.retries = append(b.retries[:i], b.retries[i+1:]...)
+			return msg
+		}
+	}
+	return nil
+}
+
+// Run consumes the queued messages with consume until ctx is done,
+// discarding the errors it returns
+func (b *MemoryBroker) Run(ctx context.Context, consume ConsumeFunc) error {
+	for {
+		if msg := b.next(0); msg != nil {
+			msg.Deliveries++
+			consume(ctx, msg, msg)
+			continue
+		}
+		select {
+		case msg := <-b.queue:
+			msg.Deliveries++
+			consume(ctx, msg, msg)
+		case <-b.retried:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Drain consumes the queued messages with consume until the queue is
+// empty, returning the first error it returned. When MaxDeliveries is zero,
+// messages are delivered up to DrainMaxDeliveries times, those still failing
+// being left queued, for Drain to end.
+func (b *MemoryBroker) Drain(ctx context.Context, consume ConsumeFunc) error {
+	limit := b.MaxDeliveries
+	if limit <= 0 {
+		limit = DrainMaxDeliveries
+	}
+	var first error
+	for {
+		msg := b.next(limit)
+		if msg == nil {
+			return first
+		}
+		msg.Deliveries++
+		if err := consume(ctx, msg, msg); err != nil && first == nil {
+			first = err
+		}
+	}
+}
+
+// DeadLetters returns the messages nacked without being requeued
+func (b *MemoryBroker) DeadLetters() []*MemoryMessage {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return append([]*MemoryMessage{}, b.dead...)
+}
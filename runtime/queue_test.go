@@ -0,0 +1,137 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newStringValue(name string) (proto.Message, bool) {
+	if name != "google.protobuf.StringValue" {
+		return nil, false
+	}
+	return &wrapperspb.StringValue{}, true
+}
+
+func TestMemoryBrokerDrain(t *testing.T) {
+	unavailable := errors.New("unavailable")
+	tests := []struct {
+		name          string
+		maxDeliveries int
+		msgType       string
+		payload       string
+		dispatchErr   error
+		deliveries    int
+		dead          bool
+	}{
+		{
+			name:       "acks handled messages",
+			msgType:    "google.protobuf.StringValue",
+			payload:    `"a"`,
+			deliveries: 1,
+		},
+		{
+			name:       "dead letters unknown commands",
+			msgType:    "unknown",
+			deliveries: 1,
+			dead:       true,
+		},
+		{
+			name:       "dead letters malformed messages",
+			msgType:    "google.protobuf.StringValue",
+			payload:    `{`,
+			deliveries: 1,
+			dead:       true,
+		},
+		{
+			name:        "dead letters client errors",
+			msgType:     "google.protobuf.StringValue",
+			payload:     `"a"`,
+			dispatchErr: NewError(http.StatusConflict, errors.New("conflict")),
+			deliveries:  1,
+			dead:        true,
+		},
+		{
+			name:          "dead letters after max deliveries",
+			maxDeliveries: 3,
+			msgType:       "google.protobuf.StringValue",
+			payload:       `"a"`,
+			dispatchErr:   unavailable,
+			deliveries:    3,
+			dead:          true,
+		},
+		{
+			name:        "bounds deliveries without max deliveries",
+			msgType:     "google.protobuf.StringValue",
+			payload:     `"a"`,
+			dispatchErr: unavailable,
+			deliveries:  DrainMaxDeliveries,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewMemoryBroker(1)
+			b.MaxDeliveries = tt.maxDeliveries
+			ctx := context.Background()
+			if err := b.Publish(ctx, tt.msgType, "", []byte(tt.payload)); err != nil {
+				t.Fatal(err)
+			}
+			deliveries := 0
+			b.Drain(ctx, func(ctx context.Context, msg Message, acker Acker) error {
+				deliveries++
+				if deliveries == 1 {
+					// fills the queue, which requeuing must not block on
+					if err := b.Publish(ctx, "filler", "", nil); err != nil {
+						return err
+					}
+				}
+				if msg.Header(MessageTypeHeader) == "filler" {
+					return acker.Ack(ctx)
+				}
+				return Consume(ctx, msg, acker, newStringValue, func(context.Context, proto.Message) (proto.Message, error) {
+					return nil, tt.dispatchErr
+				})
+			})
+			// the filler is delivered once
+			if deliveries-1 != tt.deliveries {
+				t.Errorf("%d deliveries, want %d", deliveries-1, tt.deliveries)
+			}
+			if dead := len(b.DeadLetters()) == 1; dead != tt.dead {
+				t.Errorf("dead lettered %v, want %v", dead, tt.dead)
+			}
+		})
+	}
+}
+
+func TestMemoryBrokerRunUnbuffered(t *testing.T) {
+	b := NewMemoryBroker(0)
+	b.MaxDeliveries = 2
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		b.Publish(ctx, "google.protobuf.StringValue", "", []byte(`"a"`))
+	}()
+	done := make(chan struct{})
+	go b.Run(ctx, func(ctx context.Context, msg Message, acker Acker) error {
+		err := Consume(ctx, msg, acker, newStringValue, func(context.Context, proto.Message) (proto.Message, error) {
+			return nil, errors.New("unavailable")
+		})
+		if len(b.DeadLetters()) == 1 {
+			close(done)
+		}
+		return err
+	})
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("message not dead lettered")
+	}
+	if n := b.DeadLetters()[0].Deliveries; n != 2 {
+		t.Errorf("%d deliveries, want 2", n)
+	}
+}
@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// RedactedString replaces the value of sensitive string fields
+const RedactedString = "[REDACTED]"
+
+// Redactor is implemented by the messages with sensitive fields, the
+// generated Redact method masking them in place
+type Redactor interface {
+	Redact()
+}
+
+// Redact returns msg with its sensitive fields masked, a masked copy when it
+// has any so that msg itself is left as is
+func Redact(msg proto.Message) proto.Message {
+	if _, ok := msg.(Redactor); !ok {
+		return msg
+	}
+	masked := proto.Clone(msg)
+	masked.(Redactor).Redact()
+	return masked
+}
+
+// RedactField masks the sensitive fields of msg in place when it is a
+// Redactor, for the generated Redact methods of messages with fields of types
+// generated in another run
+func RedactField(msg proto.Message) {
+	if r, ok := msg.(Redactor); ok {
+		r.Redact()
+	}
+}
+
+// Redacted is the slog value of a message, logged as json with its sensitive
+// fields masked
+type Redacted struct {
+	Message proto.Message
+}
+
+var _ slog.LogValuer = Redacted{}
+
+// LogValue masks and marshals the message only when it is logged
+func (r Redacted) LogValue() slog.Value {
+	if r.Message == nil {
+		return slog.AnyValue(nil)
+	}
+	raw, err := protojson.Marshal(Redact(r.Message))
+	if err != nil {
+		return slog.StringValue(err.Error())
+	}
+	return slog.AnyValue(json.RawMessage(raw))
+}
@@ -0,0 +1,98 @@
+package runtime_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"techunicorn.com/protoc-gen-gocqrshttp/runtime"
+	"techunicorn.com/protoc-gen-gocqrshttp/runtime/internal/redacttest"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+		want proto.Message
+	}{
+		{
+			name: "message without sensitive fields",
+			msg:  wrapperspb.String("secret"),
+			want: wrapperspb.String("secret"),
+		},
+		{
+			name: "empty sensitive fields",
+			msg:  &redacttest.Account{Name: "alice"},
+			want: &redacttest.Account{Name: "alice"},
+		},
+		{
+			name: "sensitive fields",
+			msg: &redacttest.Account{
+				Name:     "alice",
+				Password: "hunter2",
+				Pin:      proto.String("1234"),
+			},
+			want: &redacttest.Account{
+				Name:     "alice",
+				Password: runtime.RedactedString,
+				Pin:      proto.String(runtime.RedactedString),
+			},
+		},
+		{
+			name: "nested messages",
+			msg: &redacttest.Account{
+				Card:  &redacttest.Card{Number: "4111", Token: []byte("t"), Holder: "alice"},
+				Cards: []*redacttest.Card{{Number: "4222"}, nil},
+				Saved: map[string]*redacttest.Card{"home": {Token: []byte("t")}},
+			},
+			want: &redacttest.Account{
+				Card:  &redacttest.Card{Number: runtime.RedactedString, Holder: "alice"},
+				Cards: []*redacttest.Card{{Number: runtime.RedactedString}, nil},
+				Saved: map[string]*redacttest.Card{"home": {}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := proto.Clone(tt.msg)
+			got := runtime.Redact(tt.msg)
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("Redact = %v, want %v", got, tt.want)
+			}
+			if !proto.Equal(tt.msg, original) {
+				t.Errorf("message changed to %v", tt.msg)
+			}
+		})
+	}
+}
+
+func TestRedactedLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	msg := &redacttest.Account{Name: "alice", Password: "hunter2"}
+	logger.Info("request", "input", runtime.Redacted{Message: msg}, "empty", runtime.Redacted{})
+
+	var record struct {
+		Input map[string]string `json:"input"`
+		Empty any               `json:"empty"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Input["password"] != runtime.RedactedString || record.Input["name"] != "alice" {
+		t.Errorf("input = %v", record.Input)
+	}
+	if record.Empty != nil {
+		t.Errorf("empty = %v", record.Empty)
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("sensitive field logged: %s", buf.String())
+	}
+	if msg.Password != "hunter2" {
+		t.Errorf("message changed to %v", msg)
+	}
+}
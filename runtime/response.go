@@ -0,0 +1,194 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrNoResponse is returned when setting the response of a context not
+// handed to the application by a generated handler, such as for batches and
+// queues
+var ErrNoResponse = errors.New("no response to set in context")
+
+type responseContextKey struct{}
+
+// Response is the status and headers the application sets on the response
+// of a request
+type Response struct {
+	mu     sync.Mutex
+	status int
+	header http.Header
+}
+
+// WithResponse returns a copy of c through which the application sets the
+// response of a request
+func WithResponse(c context.Context) (context.Context, *Response) {
+	r := &Response{header: http.Header{}}
+	return context.WithValue(c, responseContextKey{}, r), r
+}
+
+// responseFrom returns the response carried by c
+func responseFrom(c context.Context) (*Response, error) {
+	r, ok := c.Value(responseContextKey{}).(*Response)
+	if !ok {
+		return nil, ErrNoResponse
+	}
+	return r, nil
+}
+
+// SetHeader sets a header of the response of the request c was handed out
+// for, which is written whether the rpc succeeds or fails
+func SetHeader(c context.Context, key string, value string) error {
+	r, err := responseFrom(c)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header.Set(key, value)
+	return nil
+}
+
+// SetStatus sets the status of the successful response of the request c was
+// handed out for, replacing the status of its rpc. Only 2xx statuses are
+// accepted, redirections having no Location to point to.
+func SetStatus(c context.Context, status int) error {
+	if status < 200 || status > 299 {
+		return fmt.Errorf("status %d is not a 2xx status", status)
+	}
+	r, err := responseFrom(c)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+	return nil
+}
+
+// Status returns the status set by the application, or status when it set
+// none
+func (r *Response) Status(status int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status != 0 {
+		return r.status
+	}
+	return status
+}
+
+// WriteHeader writes the headers set by the application to the response of
+// a request
+func (r *Response) WriteHeader(ctx *gin.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, values := range r.header {
+		ctx.Writer.Header()[key] = values
+	}
+}
+
+// HeaderField maps a field of a response to a header
+type HeaderField struct {
+	// Field is the name of the mapped field
+	Field protoreflect.Name
+	// Header is the name of the header
+	Header string
+}
+
+// writeHeaderFields writes the set fields of msg mapped to headers, returning
+// msg without them
+func writeHeaderFields(
+	ctx *gin.Context,
+	msg proto.Message,
+	fields []HeaderField,
+) (proto.Message, error) {
+	if len(fields) == 0 {
+		return msg, nil
+	}
+	body := proto.Clone(msg)
+	m := body.ProtoReflect()
+	for _, field := range fields {
+		fd := m.Descriptor().Fields().ByName(field.Field)
+		if fd == nil {
+			return nil, fmt.Errorf("%s has no field %s", m.Descriptor().FullName(), field.Field)
+		}
+		if !m.Has(fd) {
+			continue
+		}
+		ctx.Header(field.Header, formatValue(fd, m.Get(fd)))
+		m.Clear(fd)
+	}
+	return body, nil
+}
+
+// formatValue converts the value of a scalar field to the text of a header
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		return string(v.Bytes())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return fmt.Sprint(v.Enum())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// stripJSONFields removes the fields mapped to headers from a json body,
+// which holds them when the codec emits unpopulated fields
+func stripJSONFields(
+	raw []byte,
+	msg proto.Message,
+	fields []HeaderField,
+) ([]byte, error) {
+	if len(fields) == 0 {
+		return raw, nil
+	}
+	names := map[string]bool{}
+	descriptors := msg.ProtoReflect().Descriptor().Fields()
+	for _, field := range fields {
+		if fd := descriptors.ByName(field.Field); fd != nil {
+			names[fd.JSONName()] = true
+			names[string(fd.Name())] = true
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	out := bytes.NewBufferString("{")
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		name, _ := key.(string)
+		if names[name] {
+			continue
+		}
+		if out.Len() > 1 {
+			out.WriteByte(',')
+		}
+		quoted, _ := json.Marshal(name)
+		out.Write(quoted)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestSetStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   int
+		err    bool
+	}{
+		{status: http.StatusOK, want: http.StatusOK},
+		{status: http.StatusCreated, want: http.StatusCreated},
+		{status: http.StatusNoContent, want: http.StatusNoContent},
+		{status: http.StatusFound, want: http.StatusAccepted, err: true},
+		{status: http.StatusNotModified, want: http.StatusAccepted, err: true},
+		{status: http.StatusBadRequest, want: http.StatusAccepted, err: true},
+		{status: 100, want: http.StatusAccepted, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			c, response := WithResponse(context.Background())
+			if err := SetStatus(c, tt.status); (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if got := response.Status(http.StatusAccepted); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	if err := SetStatus(context.Background(), http.StatusOK); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("err = %v, want ErrNoResponse", err)
+	}
+}
+
+func TestEncodeHeaderFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		msg    *wrapperspb.StringValue
+		header string
+		body   string
+	}{
+		{name: "set", msg: wrapperspb.String("a"), header: "a", body: `{}`},
+		{name: "unset", msg: wrapperspb.String(""), header: "", body: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+			err := Encode(ctx, Codecs[0], http.StatusOK, tt.msg, EncodeOptions{
+				HeaderFields: []HeaderField{{Field: "value", Header: "X-Value"}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Header().Get("X-Value"); got != tt.header {
+				t.Errorf("X-Value = %q, want %q", got, tt.header)
+			}
+			if got := w.Body.String(); got != tt.body {
+				t.Errorf("body = %s, want %s", got, tt.body)
+			}
+			if tt.msg.Value != tt.header {
+				t.Error("message modified")
+			}
+		})
+	}
+}
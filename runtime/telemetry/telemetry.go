@@ -0,0 +1,176 @@
+// Package telemetry traces and measures the requests of the generated
+// handlers with OpenTelemetry
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"techunicorn.com/protoc-gen-gocqrshttp/runtime"
+)
+
+// instrumentationName names the tracer and meter of the observer
+const instrumentationName = "techunicorn.com/protoc-gen-gocqrshttp/runtime/telemetry"
+
+// Attributes of the spans and measurements of requests
+const (
+	ServiceKey    = attribute.Key("rpc.service")
+	MethodKey     = attribute.Key("rpc.method")
+	KindKey       = attribute.Key("cqrs.kind")
+	RouteKey      = attribute.Key("http.route")
+	StatusCodeKey = attribute.Key("http.status_code")
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// Option configures an Observer
+type Option func(*config)
+
+// WithTracerProvider sets the provider of the tracer, the global one being
+// used by default
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the provider of the meter, the global one being
+// used by default
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagator sets how the trace context is extracted from requests,
+// W3C trace context and baggage being used by default
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// Observer is a runtime.Observer wrapping each request in a span named after
+// its rpc, and recording the duration and sizes of requests
+type Observer struct {
+	tracer       trace.Tracer
+	propagator   propagation.TextMapPropagator
+	duration     metric.Float64Histogram
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+}
+
+var _ runtime.Observer = (*Observer)(nil)
+
+// New creates an Observer, which is a no-op until the global providers are
+// set when none is given
+func New(opts ...Option) (*Observer, error) {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagator: propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram(
+		"http.server.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of the requests to the rpcs"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	requestSize, err := meter.Int64Histogram(
+		"http.server.request.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of the request bodies of the rpcs"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	responseSize, err := meter.Int64Histogram(
+		"http.server.response.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of the response bodies of the rpcs"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Observer{
+		tracer:       c.tracerProvider.Tracer(instrumentationName),
+		propagator:   c.propagator,
+		duration:     duration,
+		requestSize:  requestSize,
+		responseSize: responseSize,
+	}, nil
+}
+
+// Observe starts the span of a request, continuing the trace of its
+// headers, and hands it to the application through the request context
+func (o *Observer) Observe(ctx *gin.Context, rpc runtime.RPCInfo) func() {
+	attrs := []attribute.KeyValue{
+		ServiceKey.String(rpc.Service),
+		MethodKey.String(rpc.Method),
+		KindKey.String(rpc.Kind),
+		RouteKey.String(rpc.Route),
+	}
+	parent := o.propagator.Extract(
+		ctx.Request.Context(),
+		propagation.HeaderCarrier(ctx.Request.Header),
+	)
+	c, span := o.tracer.Start(
+		parent,
+		rpc.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+	bag := baggage.FromContext(parent)
+	runtime.WrapContext(ctx, func(inner context.Context) context.Context {
+		return trace.ContextWithSpan(baggage.ContextWithBaggage(inner, bag), span)
+	})
+	start := time.Now()
+
+	return func() {
+		status := runtime.ResponseStatus(ctx)
+		attrs := append(attrs, StatusCodeKey.Int(status))
+		span.SetAttributes(StatusCodeKey.Int(status))
+		for _, err := range ctx.Errors {
+			span.RecordError(err.Err)
+		}
+		if status >= 500 {
+			description := ""
+			if last := ctx.Errors.Last(); last != nil {
+				description = last.Error()
+			}
+			span.SetStatus(codes.Error, description)
+		}
+		span.End()
+
+		set := metric.WithAttributes(attrs...)
+		o.duration.Record(c, time.Since(start).Seconds(), set)
+		if size := ctx.Request.ContentLength; size >= 0 {
+			o.requestSize.Record(c, size, set)
+		}
+		if size := ctx.Writer.Size(); size >= 0 {
+			o.responseSize.Record(c, int64(size), set)
+		}
+	}
+}
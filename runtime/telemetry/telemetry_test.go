@@ -0,0 +1,168 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"techunicorn.com/protoc-gen-gocqrshttp/runtime"
+)
+
+func TestObserver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rpc := runtime.RPCInfo{
+		Service: "orders.Orders",
+		Method:  "orders.Orders.PlaceOrder",
+		Kind:    "command",
+		Route:   "/orders/place-order",
+	}
+	parent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	tests := []struct {
+		name        string
+		traceparent string
+		handle      func(ctx *gin.Context)
+		status      int
+		code        codes.Code
+		errors      int
+		// unwritten leaves the size of the response unmeasured
+		unwritten bool
+	}{
+		{
+			name: "succeeds",
+			handle: func(ctx *gin.Context) {
+				ctx.String(http.StatusCreated, "created")
+			},
+			status: http.StatusCreated,
+			code:   codes.Unset,
+		},
+		{
+			name:        "continues the trace of the request",
+			traceparent: parent,
+			handle: func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "ok")
+			},
+			status: http.StatusOK,
+			code:   codes.Unset,
+		},
+		{
+			name: "fails with a client error",
+			handle: func(ctx *gin.Context) {
+				runtime.Error(ctx, runtime.NewError(http.StatusConflict, errors.New("conflict")))
+			},
+			status: http.StatusConflict,
+			code:   codes.Unset,
+			errors: 1,
+		},
+		{
+			name: "fails with an error left to the middlewares",
+			handle: func(ctx *gin.Context) {
+				runtime.Error(ctx, errors.New("database is down"))
+			},
+			status:    http.StatusInternalServerError,
+			code:      codes.Error,
+			errors:    1,
+			unwritten: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := tracetest.NewInMemoryExporter()
+			tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+			reader := sdkmetric.NewManualReader()
+			meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+			o, err := New(WithTracerProvider(tracerProvider), WithMeterProvider(meterProvider))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, rpc.Route, strings.NewReader("{}"))
+			if tt.traceparent != "" {
+				ctx.Request.Header.Set("traceparent", tt.traceparent)
+			}
+			end := o.Observe(ctx, rpc)
+			inner := trace.SpanFromContext(ctx.Request.Context())
+			tt.handle(ctx)
+			end()
+
+			got := spans.GetSpans()
+			if len(got) != 1 {
+				t.Fatalf("%d spans, want 1", len(got))
+			}
+			span := got[0]
+			if span.Name != rpc.Method || span.SpanKind != trace.SpanKindServer {
+				t.Errorf("span %s of kind %s", span.Name, span.SpanKind)
+			}
+			if inner.SpanContext().SpanID() != span.SpanContext.SpanID() {
+				t.Error("span not handed to the application")
+			}
+			if tt.traceparent != "" && span.Parent.TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+				t.Errorf("parent = %s", span.Parent.TraceID())
+			}
+			attrs := attribute.NewSet(span.Attributes...)
+			for key, want := range map[attribute.Key]attribute.Value{
+				MethodKey:     attribute.StringValue(rpc.Method),
+				KindKey:       attribute.StringValue(rpc.Kind),
+				RouteKey:      attribute.StringValue(rpc.Route),
+				StatusCodeKey: attribute.IntValue(tt.status),
+			} {
+				if v, _ := attrs.Value(key); v != want {
+					t.Errorf("%s = %s, want %s", key, v.Emit(), want.Emit())
+				}
+			}
+			if span.Status.Code != tt.code {
+				t.Errorf("span status = %s, want %s", span.Status.Code, tt.code)
+			}
+			if len(span.Events) != tt.errors {
+				t.Errorf("%d errors recorded, want %d", len(span.Events), tt.errors)
+			}
+
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatal(err)
+			}
+			counts := map[string]uint64{}
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					hist, ok := m.Data.(metricdata.Histogram[float64])
+					if ok {
+						for _, dp := range hist.DataPoints {
+							counts[m.Name] += dp.Count
+							if v, _ := dp.Attributes.Value(StatusCodeKey); v.AsInt64() != int64(tt.status) {
+								t.Errorf("%s status = %d", m.Name, v.AsInt64())
+							}
+						}
+					}
+					if hist, ok := m.Data.(metricdata.Histogram[int64]); ok {
+						for _, dp := range hist.DataPoints {
+							counts[m.Name] += dp.Count
+						}
+					}
+				}
+			}
+			for name, want := range map[string]uint64{
+				"http.server.duration":      1,
+				"http.server.request.size":  1,
+				"http.server.response.size": 1,
+			} {
+				if name == "http.server.response.size" && tt.unwritten {
+					want = 0
+				}
+				if counts[name] != want {
+					t.Errorf("%d %s measurements, want %d", counts[name], name, want)
+				}
+			}
+		})
+	}
+}
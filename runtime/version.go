@@ -0,0 +1,11 @@
+package runtime
+
+// Version is the version of this package, bumped whenever the generated
+// code requires something it did not provide before
+const Version = 1
+
+// SupportPackageIsVersion1 is referenced by generated code to assert at
+// compile time that this package is recent enough. Older versions of the
+// package do not declare it, newer versions keep declaring it for as long as
+// they support code generated against it.
+const SupportPackageIsVersion1 = true